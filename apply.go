@@ -0,0 +1,374 @@
+package nxos
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ChangeAction is the kind of change made by Apply.
+type ChangeAction string
+
+const (
+	ChangeCreate ChangeAction = "create"
+	ChangeModify ChangeAction = "modify"
+	ChangeDelete ChangeAction = "delete"
+)
+
+// AttributeChange is the old and new value of a changed attribute.
+// Old is empty for created objects.
+type AttributeChange struct {
+	Old string `json:"old,omitempty"`
+	New string `json:"new"`
+}
+
+// Change is a change of a single managed object.
+// Dn is empty for created objects whose RN cannot be derived from the desired body,
+// i.e. if neither the rn nor the dn attribute is given; Parent is always set.
+type Change struct {
+	Action     ChangeAction               `json:"action"`
+	Class      string                     `json:"class"`
+	Dn         string                     `json:"dn,omitempty"`
+	Parent     string                     `json:"parent"`
+	Attributes map[string]AttributeChange `json:"attributes,omitempty"`
+}
+
+// ApplyResult is the change report of Apply.
+type ApplyResult struct {
+	// Dn is the DN the desired state was applied to.
+	Dn string `json:"dn"`
+	// Changes are the changes made, empty if the device already matched the desired state.
+	Changes []Change `json:"changes"`
+	// Body is the minimal body posted to Dn, empty if nothing was posted.
+	Body Body `json:"-"`
+	// DryRun is true if the changes were computed but not posted.
+	DryRun bool `json:"dryRun"`
+}
+
+// Changed reports whether any change was made (or would be made in dry-run mode).
+func (r ApplyResult) Changed() bool {
+	return len(r.Changes) > 0
+}
+
+// String renders the change report, one line per object and changed attribute, e.g.
+//
+//	~ l1PhysIf sys/intf/phys-[eth1/1]
+//	    mtu: 1500 -> 9216
+func (r ApplyResult) String() string {
+	if !r.Changed() {
+		return "no change: " + r.Dn + "\n"
+	}
+	var b strings.Builder
+	for _, c := range r.Changes {
+		sym := map[ChangeAction]string{ChangeCreate: "+", ChangeModify: "~", ChangeDelete: "-"}[c.Action]
+		dn := c.Dn
+		if dn == "" {
+			dn = c.Parent + "/?"
+		}
+		fmt.Fprintf(&b, "%s %s %s\n", sym, c.Class, dn)
+		for _, k := range sortedKeys(c.Attributes) {
+			a := c.Attributes[k]
+			if c.Action == ChangeCreate {
+				fmt.Fprintf(&b, "    %s: %s\n", k, a.New)
+			} else {
+				fmt.Fprintf(&b, "    %s: %s -> %s\n", k, a.Old, a.New)
+			}
+		}
+	}
+	return b.String()
+}
+
+// ApplyOptions are the options of Apply.
+type ApplyOptions struct {
+	// DeleteUnmanaged deletes children on the device which are not part of the desired state.
+	// Only children of classes present at the same level of the desired state are deleted.
+	DeleteUnmanaged bool
+	// DryRun computes the changes without posting them.
+	DryRun bool
+	// Mods are request modifiers applied to all requests.
+	Mods []func(*Req)
+}
+
+// DeleteUnmanaged deletes children on the device which are not part of the desired state.
+// Only children of classes present at the same level of the desired state are deleted, e.g.
+// all l1PhysIf children of interfaceEntity not listed in the desired body.
+func DeleteUnmanaged() func(*ApplyOptions) {
+	return func(o *ApplyOptions) {
+		o.DeleteUnmanaged = true
+	}
+}
+
+// DryRun computes and returns the changes without posting them.
+func DryRun() func(*ApplyOptions) {
+	return func(o *ApplyOptions) {
+		o.DryRun = true
+	}
+}
+
+// ApplyReqMods sets request modifiers used for all requests made by Apply.
+func ApplyReqMods(mods ...func(*Req)) func(*ApplyOptions) {
+	return func(o *ApplyOptions) {
+		o.Mods = append(o.Mods, mods...)
+	}
+}
+
+// Apply ensures that the object at dn matches the desired body, e.g.
+//
+//	desired := nxos.Body{}.
+//	    Set("l1PhysIf.attributes.id", "eth1/1").
+//	    Set("l1PhysIf.attributes.mtu", "9216")
+//	res, err := client.Apply("sys/intf/phys-[eth1/1]", desired)
+//	if res.Changed() {
+//	    fmt.Print(res)
+//	}
+//
+// The current subtree is fetched with rsp-prop-include=config-only and compared
+// attribute by attribute and child by child. Only attributes and children set in the
+// desired body are managed; everything else on the device is left untouched unless
+// DeleteUnmanaged is passed. Attributes of existing objects which are not returned
+// by the device, i.e. no configuration properties, are ignored. Children are matched by
+// class and RN, which is taken from the rn or dn attribute if present, otherwise from
+// the naming properties of the device's objects (see Rn.MatchesAttributes).
+// A child with status "deleted" is deleted if it exists.
+//
+// Only the differences are posted, in a single request to dn. Nothing is posted if the
+// device already matches the desired state.
+func (client *Client) Apply(dn string, desired Body, opts ...func(*ApplyOptions)) (ApplyResult, error) {
+	return client.ApplyCtx(context.Background(), dn, desired, opts...)
+}
+
+// ApplyCtx ensures that the object at dn matches the desired body using the given context.
+// See Apply for details.
+func (client *Client) ApplyCtx(ctx context.Context, dn string, desired Body, opts ...func(*ApplyOptions)) (ApplyResult, error) {
+	o := ApplyOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	result := ApplyResult{Dn: dn, DryRun: o.DryRun}
+	class, want, err := singleObject(desired.Res())
+	if err != nil {
+		return result, err
+	}
+	getMods := append([]func(*Req){
+		Query("rsp-subtree", "full"),
+		Query("rsp-prop-include", "config-only"),
+	}, o.Mods...)
+	current, err := client.GetDnCtx(ctx, dn, getMods...)
+	if IsNotFound(err) {
+		current, err = Res{}, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	d := differ{deleteUnmanaged: o.DeleteUnmanaged}
+	var body string
+	var changed bool
+	if cur := current.Get(gjsonEscape(class)); cur.Exists() {
+		body, changed = d.diff(class, Dn(dn), want, cur, true)
+	} else if current.Exists() {
+		return result, fmt.Errorf("class mismatch for %s: desired %s: %w", dn, class, ErrInvalidArgument)
+	} else if !isDeleted(want) {
+		d.created(class, Dn(dn).Parent(), Dn(dn), want)
+		body, changed = desired.Str, true
+	}
+	result.Changes = d.changes
+	if !changed {
+		return result, nil
+	}
+	result.Body = Body{body}
+	if o.DryRun {
+		return result, nil
+	}
+	_, err = client.PostCtx(ctx, dn, body, o.Mods...)
+	return result, err
+}
+
+// differ computes the changes and minimal body between desired and current objects.
+type differ struct {
+	deleteUnmanaged bool
+	changes         []Change
+}
+
+// diff compares the desired object with the current object at dn and returns the
+// minimal body to post and whether anything changed. The naming attributes of
+// children are kept so that they can be identified by the device.
+func (d *differ) diff(class string, dn Dn, want, cur gjson.Result, root bool) (string, bool) {
+	key := gjsonEscape(class)
+	body := Body{}.SetRaw(key+".attributes", "{}")
+	if !root {
+		body = body.Set(key+".attributes.rn", string(dn.Rn()))
+	}
+	if isDeleted(want) {
+		d.changes = append(d.changes, Change{Action: ChangeDelete, Class: class, Dn: string(dn), Parent: string(dn.Parent())})
+		return body.Set(key+".attributes.status", "deleted").Str, true
+	}
+	changed := false
+	attrs := map[string]AttributeChange{}
+	curAttrs := cur.Get("attributes")
+	want.Get("attributes").ForEach(func(k, v gjson.Result) bool {
+		switch k.Str {
+		case "dn", "rn", "status", "childAction":
+			return true
+		}
+		old := curAttrs.Get(gjsonEscape(k.Str))
+		if !old.Exists() {
+			// Not a configuration property
+			return true
+		}
+		if !root && old.String() == v.String() && isNamingValue(dn.Rn(), v.String()) {
+			body = body.Set(key+".attributes."+gjsonEscape(k.Str), v.String())
+		}
+		if old.String() == v.String() {
+			return true
+		}
+		attrs[k.Str] = AttributeChange{Old: old.String(), New: v.String()}
+		body = body.Set(key+".attributes."+gjsonEscape(k.Str), v.String())
+		return true
+	})
+	if len(attrs) > 0 {
+		changed = true
+		d.changes = append(d.changes, Change{Action: ChangeModify, Class: class, Dn: string(dn), Parent: string(dn.Parent()), Attributes: attrs})
+	}
+
+	curChildren := cur.Get("children").Array()
+	matched := make([]bool, len(curChildren))
+	managed := map[string]bool{}
+	for _, child := range want.Get("children").Array() {
+		childClass, childWant, err := singleObject(child)
+		if err != nil {
+			continue
+		}
+		managed[childClass] = true
+		i := matchChild(childClass, childWant, curChildren, matched)
+		if i < 0 {
+			if isDeleted(childWant) {
+				continue
+			}
+			d.created(childClass, dn, childDn(dn, childWant), childWant)
+			body = body.SetRaw(key+".children.-1", child.Raw)
+			changed = true
+			continue
+		}
+		matched[i] = true
+		childCur := curChildren[i].Get(gjsonEscape(childClass))
+		childBody, childChanged := d.diff(childClass, dn.Child(ObjectRn(childCur)), childWant, childCur, false)
+		if childChanged {
+			body = body.SetRaw(key+".children.-1", childBody)
+			changed = true
+		}
+	}
+	if d.deleteUnmanaged {
+		for i, child := range curChildren {
+			childClass, childCur, err := singleObject(child)
+			if err != nil || matched[i] || !managed[childClass] {
+				continue
+			}
+			rn := ObjectRn(childCur)
+			d.changes = append(d.changes, Change{Action: ChangeDelete, Class: childClass, Dn: string(dn.Child(rn)), Parent: string(dn)})
+			del := Body{}.
+				Set(gjsonEscape(childClass)+".attributes.rn", string(rn)).
+				Set(gjsonEscape(childClass)+".attributes.status", "deleted")
+			body = body.SetRaw(key+".children.-1", del.Str)
+			changed = true
+		}
+	}
+	return body.Str, changed
+}
+
+// created records the creation of an object and all its children.
+func (d *differ) created(class string, parent, dn Dn, want gjson.Result) {
+	attrs := map[string]AttributeChange{}
+	want.Get("attributes").ForEach(func(k, v gjson.Result) bool {
+		if k.Str != "status" {
+			attrs[k.Str] = AttributeChange{New: v.String()}
+		}
+		return true
+	})
+	d.changes = append(d.changes, Change{Action: ChangeCreate, Class: class, Dn: string(dn), Parent: string(parent), Attributes: attrs})
+	for _, child := range want.Get("children").Array() {
+		childClass, childWant, err := singleObject(child)
+		if err != nil || isDeleted(childWant) {
+			continue
+		}
+		childParent := dn
+		if dn == "" {
+			childParent = parent
+		}
+		d.created(childClass, childParent, childDn(dn, childWant), childWant)
+	}
+}
+
+// matchChild returns the index of the unmatched current child matching the desired child, or -1.
+func matchChild(class string, want gjson.Result, children []gjson.Result, matched []bool) int {
+	wantRn := ObjectRn(want)
+	for i, child := range children {
+		cur := child.Get(gjsonEscape(class))
+		if matched[i] || !cur.Exists() {
+			continue
+		}
+		rn := ObjectRn(cur)
+		if wantRn != "" {
+			if rn == wantRn {
+				return i
+			}
+			continue
+		}
+		if rn.MatchesAttributes(cur.Get("attributes"), want.Get("attributes")) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isNamingValue reports whether the value is one of the naming values of the RN.
+func isNamingValue(rn Rn, v string) bool {
+	for _, k := range rn.Keys() {
+		if k == v {
+			return true
+		}
+	}
+	return false
+}
+
+// childDn returns the DN of a desired child, or an empty DN if it cannot be derived.
+func childDn(parent Dn, want gjson.Result) Dn {
+	if dn := want.Get("attributes.dn").Str; dn != "" {
+		return Dn(dn)
+	}
+	if rn := want.Get("attributes.rn").Str; rn != "" && parent != "" {
+		return parent.Child(Rn(rn))
+	}
+	return ""
+}
+
+// singleObject returns the class and content of a {"class":{...}} object.
+func singleObject(obj gjson.Result) (string, gjson.Result, error) {
+	var class string
+	var content gjson.Result
+	n := 0
+	obj.ForEach(func(k, v gjson.Result) bool {
+		class, content = k.Str, v
+		n++
+		return true
+	})
+	if n != 1 {
+		return "", content, fmt.Errorf("expected a single object, got %d: %w", n, ErrInvalidArgument)
+	}
+	return class, content, nil
+}
+
+func isDeleted(obj gjson.Result) bool {
+	return strings.Contains(obj.Get("attributes.status").Str, "deleted")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+package nxos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const testApplyIntf = `{"imdata":[{"interfaceEntity":{"attributes":{"dn":"sys/intf","descr":""},"children":[
+	{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/1]","id":"eth1/1","mtu":"1500","descr":"uplink"}}},
+	{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/2]","id":"eth1/2","mtu":"9216"}}},
+	{"l1Loopback":{"attributes":{"dn":"sys/intf/lb-[lo0]","id":"lo0"}}}
+]}}]}`
+
+// TestClientApply tests the Client::Apply method.
+func TestClientApply(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// No change
+	gock.New(testURL).
+		Get("/api/mo/sys/intf.json").
+		MatchParam("rsp-subtree", "full").
+		MatchParam("rsp-prop-include", "config-only").
+		Reply(200).
+		BodyString(testApplyIntf)
+	res, err := client.Apply("sys/intf", Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.id", "eth1/1").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.mtu", "1500"))
+	assert.NoError(t, err)
+	assert.False(t, res.Changed())
+	assert.Equal(t, "no change: sys/intf\n", res.String())
+	assert.True(t, gock.IsDone())
+
+	// Minimal change
+	gock.New(testURL).Get("/api/mo/sys/intf.json").Reply(200).BodyString(testApplyIntf)
+	gock.New(testURL).Post("/api/mo/sys/intf.json").Reply(200)
+	res, err = client.Apply("sys/intf", Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.id", "eth1/1").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.mtu", "9216").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.descr", "uplink"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{
+		Action:     ChangeModify,
+		Class:      "l1PhysIf",
+		Dn:         "sys/intf/phys-[eth1/1]",
+		Parent:     "sys/intf",
+		Attributes: map[string]AttributeChange{"mtu": {Old: "1500", New: "9216"}},
+	}}, res.Changes)
+	assert.Equal(t, "~ l1PhysIf sys/intf/phys-[eth1/1]\n    mtu: 1500 -> 9216\n", res.String())
+	assert.JSONEq(t, `{"interfaceEntity":{"attributes":{},"children":[{"l1PhysIf":{"attributes":{"rn":"phys-[eth1/1]","id":"eth1/1","mtu":"9216"}}}]}}`, res.Body.Str)
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientApplyRepeated tests that applying the device state again posts nothing.
+func TestClientApplyRepeated(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// Attributes not returned by the config-only read are not compared
+	desired := Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.id", "eth1/2").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.mtu", "9216").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.operSt", "up").
+		Set("interfaceEntity.children.1.l1Loopback.attributes.id", "lo0")
+	for i := 0; i < 2; i++ {
+		gock.New(testURL).Get("/api/mo/sys/intf.json").Reply(200).BodyString(testApplyIntf)
+		res, err := client.Apply("sys/intf", desired)
+		assert.NoError(t, err)
+		assert.False(t, res.Changed())
+		assert.Empty(t, res.Body.Str)
+	}
+	assert.True(t, gock.IsDone())
+
+	// Children are matched on naming properties only
+	gock.New(testURL).Get("/api/mo/sys/intf.json").Reply(200).BodyString(testApplyIntf)
+	res, err := client.Apply("sys/intf", Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.id", "eth1/3").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.descr", "eth1/1"), DryRun())
+	assert.NoError(t, err)
+	assert.Len(t, res.Changes, 1)
+	assert.Equal(t, ChangeCreate, res.Changes[0].Action)
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientApplyCreate tests the Client::Apply method for objects not on the device.
+func TestClientApplyCreate(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	desired := Body{}.
+		Set("l1PhysIf.attributes.id", "eth1/3").
+		Set("l1PhysIf.attributes.mtu", "9216").
+		Set("l1PhysIf.children.0.ethpmPhysIf.attributes.descr", "x")
+	gock.New(testURL).Get("/api/mo/sys/intf/phys-\\[eth1/3\\].json").Reply(200).BodyString(`{"imdata":[]}`)
+	gock.New(testURL).Post("/api/mo/sys/intf/phys-\\[eth1/3\\].json").Reply(200)
+	res, err := client.Apply("sys/intf/phys-[eth1/3]", desired)
+	assert.NoError(t, err)
+	assert.Len(t, res.Changes, 2)
+	assert.Equal(t, ChangeCreate, res.Changes[0].Action)
+	assert.Equal(t, "sys/intf/phys-[eth1/3]", res.Changes[0].Dn)
+	assert.Equal(t, "sys/intf", res.Changes[0].Parent)
+	assert.Equal(t, "", res.Changes[1].Dn)
+	assert.Equal(t, "sys/intf/phys-[eth1/3]", res.Changes[1].Parent)
+	assert.True(t, gock.IsDone())
+
+	// Not found error from device
+	gock.New(testURL).Get("/api/mo/sys/intf/phys-\\[eth1/3\\].json").Reply(400).
+		BodyString(`{"imdata":[{"error":{"attributes":{"code":"1","text":"Object not found"}}}]}`)
+	res, err = client.Apply("sys/intf/phys-[eth1/3]", desired, DryRun())
+	assert.NoError(t, err)
+	assert.True(t, res.Changed())
+	assert.Equal(t, desired.Str, res.Body.Str)
+
+	// Class mismatch
+	gock.New(testURL).Get("/api/mo/sys/intf.json").Reply(200).BodyString(testApplyIntf)
+	_, err = client.Apply("sys/intf", Body{}.Set("l1PhysIf.attributes.id", "eth1/1"))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	// Invalid body
+	_, err = client.Apply("sys/intf", Body{})
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
+
+// TestClientApplyDelete tests deletion of children by Client::Apply.
+func TestClientApplyDelete(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// Explicit deletion
+	gock.New(testURL).Get("/api/mo/sys/intf.json").Reply(200).BodyString(testApplyIntf)
+	res, err := client.Apply("sys/intf", Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.rn", "phys-[eth1/2]").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.status", "deleted").
+		Set("interfaceEntity.children.1.l1PhysIf.attributes.rn", "phys-[eth1/9]").
+		Set("interfaceEntity.children.1.l1PhysIf.attributes.status", "deleted"),
+		DryRun())
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Action: ChangeDelete, Class: "l1PhysIf", Dn: "sys/intf/phys-[eth1/2]", Parent: "sys/intf"}}, res.Changes)
+	assert.JSONEq(t, `{"interfaceEntity":{"attributes":{},"children":[{"l1PhysIf":{"attributes":{"rn":"phys-[eth1/2]","status":"deleted"}}}]}}`, res.Body.Str)
+
+	// Unmanaged children of managed classes
+	gock.New(testURL).Get("/api/mo/sys/intf.json").Reply(200).BodyString(testApplyIntf)
+	res, err = client.Apply("sys/intf", Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.id", "eth1/1"),
+		DeleteUnmanaged(), DryRun())
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{Action: ChangeDelete, Class: "l1PhysIf", Dn: "sys/intf/phys-[eth1/2]", Parent: "sys/intf"}}, res.Changes)
+	assert.Equal(t, "- l1PhysIf sys/intf/phys-[eth1/2]\n", res.String())
+	assert.True(t, gock.IsDone())
+}
@@ -0,0 +1,357 @@
+package nxos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// BatchOp is the kind of change of a batch item.
+type BatchOp string
+
+const (
+	// BatchSet creates or updates an object (status not set).
+	BatchSet BatchOp = "set"
+	// BatchCreate creates an object and fails if it exists (status "created").
+	BatchCreate BatchOp = "created"
+	// BatchModify updates an object and fails if it does not exist (status "modified").
+	BatchModify BatchOp = "modified"
+	// BatchDelete deletes an object (status "deleted").
+	BatchDelete BatchOp = "deleted"
+)
+
+// BatchItem is a single change of a batch.
+type BatchItem struct {
+	// Index is the position of the item in the batch.
+	Index int
+	// Op is the kind of change.
+	Op BatchOp
+	// Dn is the DN of the object.
+	Dn Dn
+	// Class is the class of the object.
+	Class string
+	// Body is the object body in the {"class":{"attributes":{...}}} format.
+	Body Body
+}
+
+// BatchError is returned by Batch.Submit if the device rejects the batch. Item is the
+// batch item the error refers to, or nil if it cannot be determined from the error.
+type BatchError struct {
+	Item *BatchItem
+	Err  error
+}
+
+func (e *BatchError) Error() string {
+	if e.Item == nil {
+		return fmt.Sprintf("batch failed: %s", e.Err)
+	}
+	return fmt.Sprintf("batch item %d (%s %s %s) failed: %s", e.Item.Index, e.Item.Op, e.Item.Class, e.Item.Dn, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// wellKnownClasses are the classes of common container objects, used to build the
+// hierarchy between the common ancestor and the batch items without a lookup.
+var wellKnownClasses = map[Dn]string{
+	"sys":           "topSystem",
+	"sys/intf":      "interfaceEntity",
+	"sys/bgp":       "bgpEntity",
+	"sys/bgp/inst":  "bgpInst",
+	"sys/ospf":      "ospfEntity",
+	"sys/ipv4":      "ipv4Entity",
+	"sys/ipv4/inst": "ipv4Inst",
+	"sys/bd":        "bdEntity",
+	"sys/fm":        "fmEntity",
+	"sys/userext":   "aaaUserEp",
+}
+
+// Batch accumulates changes of multiple objects and submits them in a single POST
+// request to their common ancestor, so that the device applies them together, e.g.
+//
+//	batch := client.NewBatch().
+//	    Set("sys/inst-[VRF1]", nxos.Body{}.Set("l3Inst.attributes.name", "VRF1")).
+//	    Set("sys/intf/phys-[eth1/1]/rtvrfMbr", nxos.Body{}.Set("nwRtVrfMbr.attributes.tDn", "sys/inst-[VRF1]")).
+//	    Delete("sys/intf/phys-[eth1/2]/rtvrfMbr", "nwRtVrfMbr")
+//	_, err := batch.Submit()
+//
+// Objects between the common ancestor and the items are included without attributes.
+// Their classes are taken from other items, a list of well-known containers, classes
+// registered with Container, or otherwise looked up on the device.
+type Batch struct {
+	client     *Client
+	items      []BatchItem
+	containers map[Dn]string
+	err        error
+}
+
+// NewBatch creates an empty batch.
+func (client *Client) NewBatch() *Batch {
+	return &Batch{client: client, containers: map[Dn]string{}}
+}
+
+// Items returns the items of the batch.
+func (b *Batch) Items() []BatchItem {
+	return b.items
+}
+
+// Set adds an object which is created or updated.
+// The body is a single object in the {"class":{"attributes":{...},"children":[...]}} format.
+func (b *Batch) Set(dn string, body Body) *Batch {
+	return b.add(BatchSet, dn, body)
+}
+
+// Create adds an object which is created. The request fails if the object exists.
+func (b *Batch) Create(dn string, body Body) *Batch {
+	return b.add(BatchCreate, dn, body)
+}
+
+// Modify adds an object which is updated. The request fails if the object does not exist.
+func (b *Batch) Modify(dn string, body Body) *Batch {
+	return b.add(BatchModify, dn, body)
+}
+
+// Delete adds an object of the given class which is deleted.
+func (b *Batch) Delete(dn, class string) *Batch {
+	return b.add(BatchDelete, dn, Body{}.SetRaw(gjsonEscape(class)+".attributes", "{}"))
+}
+
+// Container registers the class of an object between the common ancestor and the items,
+// avoiding a lookup on the device.
+func (b *Batch) Container(dn, class string) *Batch {
+	b.containers[Dn(dn)] = class
+	return b
+}
+
+func (b *Batch) add(op BatchOp, dn string, body Body) *Batch {
+	if b.err != nil {
+		return b
+	}
+	d, err := ParseDn(dn)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	class, obj, err := singleObject(body.Res())
+	if err != nil {
+		b.err = fmt.Errorf("batch item %d (%s): %w", len(b.items), dn, err)
+		return b
+	}
+	if op != BatchSet {
+		body = body.Set(gjsonEscape(class)+".attributes.status", string(op))
+	}
+	if obj.Get("attributes.status").Str == string(BatchDelete) {
+		op = BatchDelete
+	}
+	b.items = append(b.items, BatchItem{Index: len(b.items), Op: op, Dn: d, Class: class, Body: body})
+	return b
+}
+
+// batchNode is an object of the merged batch body.
+type batchNode struct {
+	dn       Dn
+	class    string
+	body     gjson.Result
+	children []*batchNode
+}
+
+func (n *batchNode) child(dn Dn) *batchNode {
+	for _, c := range n.children {
+		if c.dn == dn {
+			return c
+		}
+	}
+	c := &batchNode{dn: dn}
+	n.children = append(n.children, c)
+	return c
+}
+
+// render renders the node and its children. Children are identified by their rn attribute.
+func (n *batchNode) render(root bool) string {
+	key := gjsonEscape(n.class)
+	body := Body{}.SetRaw(key+".attributes", "{}")
+	if n.body.Exists() {
+		body = Body{n.body.Raw}
+		if !body.Res().Get(key + ".attributes").Exists() {
+			body = body.SetRaw(key+".attributes", "{}")
+		}
+	}
+	if !root {
+		body = body.Set(key+".attributes.rn", string(n.dn.Rn()))
+	}
+	for _, c := range n.children {
+		body = body.SetRaw(key+".children.-1", c.render(false))
+	}
+	return body.Str
+}
+
+// Ancestor returns the common ancestor DN of all items, which is at most sys.
+func (b *Batch) Ancestor() (Dn, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	if len(b.items) == 0 {
+		return "", fmt.Errorf("empty batch: %w", ErrInvalidArgument)
+	}
+	common := b.items[0].Dn.Rns()
+	for _, item := range b.items[1:] {
+		rns := item.Dn.Rns()
+		i := 0
+		for i < len(common) && i < len(rns) && common[i] == rns[i] {
+			i++
+		}
+		common = common[:i]
+	}
+	if len(common) == 0 {
+		return "", fmt.Errorf("batch items have no common ancestor: %w", ErrInvalidArgument)
+	}
+	return NewDn(common...), nil
+}
+
+// Build merges the items into a single body and returns it with the DN to post it to.
+func (b *Batch) Build() (Dn, Body, error) {
+	return b.BuildCtx(context.Background())
+}
+
+// BuildCtx merges the items into a single body using the given context for class lookups.
+// See Build for details.
+func (b *Batch) BuildCtx(ctx context.Context) (Dn, Body, error) {
+	ancestor, err := b.Ancestor()
+	if err != nil {
+		return "", Body{}, err
+	}
+	root := &batchNode{dn: ancestor}
+	for _, item := range b.items {
+		n := root
+		rns := item.Dn.Rns()
+		for i := len(ancestor.Rns()); i < len(rns); i++ {
+			n = n.child(NewDn(rns[:i+1]...))
+		}
+		if n.class != "" && n.class != item.Class {
+			return "", Body{}, fmt.Errorf("batch item %d: class %s conflicts with %s for %s: %w", item.Index, item.Class, n.class, item.Dn, ErrInvalidArgument)
+		}
+		n.class = item.Class
+		n.body = mergeBody(n.body, item.Body.Res(), item.Class)
+	}
+	if err := b.resolve(ctx, root); err != nil {
+		return "", Body{}, err
+	}
+	return ancestor, Body{root.render(true)}, nil
+}
+
+// mergeBody merges attributes and children of an item into an existing body.
+// A deletion replaces the existing body, and an item following a deletion replaces
+// the deletion.
+func mergeBody(existing, item gjson.Result, class string) gjson.Result {
+	key := gjsonEscape(class)
+	deleted := func(body gjson.Result) bool {
+		return body.Get(key+".attributes.status").Str == string(BatchDelete)
+	}
+	if !existing.Exists() || deleted(existing) || deleted(item) {
+		return item
+	}
+	body := Body{existing.Raw}
+	item.Get(key + ".attributes").ForEach(func(k, v gjson.Result) bool {
+		body = body.Set(key+".attributes."+gjsonEscape(k.Str), v.String())
+		return true
+	})
+	for _, child := range item.Get(key + ".children").Array() {
+		body = body.SetRaw(key+".children.-1", child.Raw)
+	}
+	return body.Res()
+}
+
+// resolve sets the classes of container nodes.
+func (b *Batch) resolve(ctx context.Context, n *batchNode) error {
+	if n.class == "" {
+		class, err := b.containerClass(ctx, n.dn)
+		if err != nil {
+			return err
+		}
+		n.class = class
+	}
+	for _, c := range n.children {
+		if err := b.resolve(ctx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Batch) containerClass(ctx context.Context, dn Dn) (string, error) {
+	if class, ok := b.containers[dn]; ok {
+		return class, nil
+	}
+	if class, ok := wellKnownClasses[dn]; ok {
+		return class, nil
+	}
+	res, err := b.client.GetMoCtx(ctx, dn, Query("query-target", "self"))
+	if err != nil && !IsNotFound(err) {
+		return "", err
+	}
+	class, _, err := singleObject(res)
+	if err != nil {
+		return "", fmt.Errorf("cannot determine class of %s, register it with Container: %w", dn, ErrInvalidArgument)
+	}
+	// Cache for subsequent builds
+	b.containers[dn] = class
+	return class, nil
+}
+
+// Submit merges the items into a single body and posts it to their common ancestor.
+// If the device rejects the batch, the error is a *BatchError referring to the item
+// named in the error text, if any.
+func (b *Batch) Submit(mods ...func(*Req)) (Res, error) {
+	return b.SubmitCtx(context.Background(), mods...)
+}
+
+// SubmitCtx submits the batch using the given context.
+// See Submit for details.
+func (b *Batch) SubmitCtx(ctx context.Context, mods ...func(*Req)) (Res, error) {
+	dn, body, err := b.BuildCtx(ctx)
+	if err != nil {
+		return Res{}, err
+	}
+	res, err := b.client.PostMoCtx(ctx, dn, body.Str, mods...)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return res, &BatchError{Item: b.attribute(apiErr.Text), Err: err}
+		}
+		return res, err
+	}
+	return res, nil
+}
+
+// attribute returns the item referred to by an error text: the item with the longest
+// DN contained in the text, otherwise the only item with its RN or class in the text.
+func (b *Batch) attribute(text string) *BatchItem {
+	var best *BatchItem
+	for i, item := range b.items {
+		if strings.Contains(text, string(item.Dn)) && (best == nil || len(item.Dn) > len(best.Dn)) {
+			best = &b.items[i]
+		}
+	}
+	if best != nil {
+		return best
+	}
+	for _, match := range []func(BatchItem) bool{
+		func(item BatchItem) bool { return strings.Contains(text, string(item.Dn.Rn())) },
+		func(item BatchItem) bool { return strings.Contains(text, item.Class) },
+	} {
+		var found []*BatchItem
+		for i, item := range b.items {
+			if match(item) {
+				found = append(found, &b.items[i])
+			}
+		}
+		if len(found) == 1 {
+			return found[0]
+		}
+	}
+	return nil
+}
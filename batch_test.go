@@ -0,0 +1,138 @@
+package nxos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// TestBatchBuild tests merging batch items into a single body.
+func TestBatchBuild(t *testing.T) {
+	client := testClient()
+
+	batch := client.NewBatch().
+		Create("sys/intf/phys-[eth1/1]/rtvrfMbr", Body{}.Set("nwRtVrfMbr.attributes.tDn", "sys/inst-[VRF1]")).
+		Set("sys/intf/phys-[eth1/1]", Body{}.Set("l1PhysIf.attributes.mtu", "9216")).
+		Delete("sys/intf/phys-[eth1/2]", "l1PhysIf").
+		Modify("sys/intf/phys-[eth1/1]", Body{}.Set("l1PhysIf.attributes.descr", "uplink"))
+	dn, body, err := batch.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, Dn("sys/intf"), dn)
+	assert.JSONEq(t, `{"interfaceEntity":{"attributes":{},"children":[
+		{"l1PhysIf":{"attributes":{"rn":"phys-[eth1/1]","mtu":"9216","descr":"uplink","status":"modified"},"children":[
+			{"nwRtVrfMbr":{"attributes":{"rn":"rtvrfMbr","tDn":"sys/inst-[VRF1]","status":"created"}}}
+		]}},
+		{"l1PhysIf":{"attributes":{"rn":"phys-[eth1/2]","status":"deleted"}}}
+	]}}`, body.Str)
+	assert.Len(t, batch.Items(), 4)
+	assert.Equal(t, BatchDelete, batch.Items()[2].Op)
+
+	// Set after Delete of the same object replaces the deletion
+	batch = client.NewBatch().
+		Delete("sys/intf/phys-[eth1/1]", "l1PhysIf").
+		Set("sys/intf/phys-[eth1/1]", Body{}.Set("l1PhysIf.attributes.mtu", "9216")).
+		Delete("sys/intf/phys-[eth1/2]", "l1PhysIf")
+	_, body, err = batch.Build()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"interfaceEntity":{"attributes":{},"children":[
+		{"l1PhysIf":{"attributes":{"rn":"phys-[eth1/1]","mtu":"9216"}}},
+		{"l1PhysIf":{"attributes":{"rn":"phys-[eth1/2]","status":"deleted"}}}
+	]}}`, body.Str)
+
+	// Common ancestor sys with well-known and registered containers
+	batch = client.NewBatch().
+		Set("sys/inst-[VRF1]", Body{}.Set("l3Inst.attributes.name", "VRF1")).
+		Set("sys/bgp/inst/dom-[VRF1]", Body{}.Set("bgpDom.attributes.name", "VRF1")).
+		Set("sys/ospf/inst-[default]/dom-[VRF1]", Body{}.Set("ospfDom.attributes.name", "VRF1")).
+		Container("sys/ospf/inst-[default]", "ospfInst")
+	dn, body, err = batch.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, Dn("sys"), dn)
+	assert.Equal(t, "bgp", body.Res().Get("topSystem.children.1.bgpEntity.attributes.rn").Str)
+	assert.Equal(t, "dom-[VRF1]", body.Res().Get("topSystem.children.1.bgpEntity.children.0.bgpInst.children.0.bgpDom.attributes.rn").Str)
+	assert.Equal(t, "inst-[default]", body.Res().Get("topSystem.children.2.ospfEntity.children.0.ospfInst.attributes.rn").Str)
+
+	// Errors
+	_, _, err = client.NewBatch().Build()
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, _, err = client.NewBatch().
+		Set("sys/intf", Body{}.Set("interfaceEntity.attributes.descr", "")).
+		Set("userext", Body{}.Set("aaaUserEp.attributes.descr", "")).
+		Build()
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, _, err = client.NewBatch().Set("sys/intf", Body{}).Build()
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, _, err = client.NewBatch().Set("sys/intf]", Body{}.Set("interfaceEntity.attributes.descr", "")).Build()
+	assert.Error(t, err)
+	_, _, err = client.NewBatch().
+		Set("sys/intf/phys-[eth1/1]", Body{}.Set("l1PhysIf.attributes.mtu", "9216")).
+		Delete("sys/intf/phys-[eth1/1]", "l3LbRtdIf").
+		Build()
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
+
+// TestBatchLookup tests looking up container classes on the device.
+func TestBatchLookup(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).
+		Get("/api/mo/sys/ospf/inst-\\[default\\].json").
+		MatchParam("query-target", "self").
+		Reply(200).
+		BodyString(`{"imdata":[{"ospfInst":{"attributes":{"dn":"sys/ospf/inst-[default]"}}}]}`)
+	batch := client.NewBatch().
+		Set("sys/ospf/inst-[default]/dom-[default]/if-[eth1/1]", Body{}.Set("ospfIf.attributes.area", "0.0.0.0")).
+		Set("sys/ospf/inst-[default]/dom-[default]", Body{}.Set("ospfDom.attributes.name", "default")).
+		Set("sys/bgp", Body{}.Set("bgpEntity.attributes.adminSt", "enabled"))
+	_, body, err := batch.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "inst-[default]", body.Res().Get("topSystem.children.0.ospfEntity.children.0.ospfInst.attributes.rn").Str)
+	assert.True(t, gock.IsDone())
+
+	// Cached
+	_, _, err = batch.Build()
+	assert.NoError(t, err)
+
+	// Unknown container
+	gock.New(testURL).Get("/api/mo/sys/foo.json").Reply(200).BodyString(`{"imdata":[]}`)
+	_, _, err = client.NewBatch().
+		Set("sys/foo/bar", Body{}.Set("fooBar.attributes.name", "x")).
+		Set("sys/intf", Body{}.Set("interfaceEntity.attributes.descr", "")).
+		Build()
+	assert.ErrorContains(t, err, "register it with Container")
+}
+
+// TestBatchSubmit tests submitting a batch and error attribution.
+func TestBatchSubmit(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	batch := client.NewBatch().
+		Set("sys/intf/phys-[eth1/1]", Body{}.Set("l1PhysIf.attributes.mtu", "9216")).
+		Set("sys/intf/phys-[eth1/2]", Body{}.Set("l1PhysIf.attributes.mtu", "99999"))
+
+	gock.New(testURL).Post("/api/mo/sys/intf.json").Reply(200)
+	_, err := batch.Submit()
+	assert.NoError(t, err)
+
+	gock.New(testURL).Post("/api/mo/sys/intf.json").Reply(400).
+		BodyString(`{"imdata":[{"error":{"attributes":{"code":"103","text":"Property mtu of sys/intf/phys-[eth1/2] is out of range"}}}]}`)
+	_, err = batch.Submit()
+	var batchErr *BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, 1, batchErr.Item.Index)
+	assert.True(t, IsInvalidArgument(err))
+
+	// Attribution by RN
+	assert.Equal(t, 0, batch.attribute("phys-[eth1/1]: invalid").Index)
+	// Unknown item
+	gock.New(testURL).Post("/api/mo/sys/intf.json").Reply(400).
+		BodyString(`{"imdata":[{"error":{"attributes":{"code":"1","text":"l1PhysIf: invalid"}}}]}`)
+	_, err = batch.Submit()
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Nil(t, batchErr.Item)
+	assert.True(t, gock.IsDone())
+}
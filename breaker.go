@@ -0,0 +1,177 @@
+package nxos
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned when a request is rejected by an open circuit breaker.
+// If a retry is rejected, the error is joined with the error of the previous attempt.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests pass.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen lets a single probe request pass to test whether the device recovered.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker stops sending requests to a device after consecutive failures.
+// Connection errors and server errors (HTTP 500-504) count as failures, any other
+// response closes the circuit again. Once OpenTimeout has elapsed, a single probe
+// request is let through in the half-open state; only its outcome closes or reopens
+// the circuit, outcomes of requests sent before the circuit opened are ignored.
+// Use nxos.NewCircuitBreaker to create a circuit breaker and attach it to a client
+// using nxos.Breaker.
+type CircuitBreaker struct {
+	// FailureThreshold is the number of consecutive failures opening the circuit.
+	FailureThreshold int
+	// OpenTimeout is the time the circuit stays open before a probe request is allowed.
+	OpenTimeout time.Duration
+	// OnStateChange is called on every state transition. It is called while the
+	// circuit breaker is locked and must not call its methods.
+	OnStateChange func(from, to CircuitState)
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	// probe is the ticket of the pending probe request, or 0 if there is none
+	probe   uint64
+	tickets uint64
+}
+
+// NewCircuitBreaker creates a new circuit breaker opening after threshold consecutive
+// failures and allowing a probe request after openTimeout.
+func NewCircuitBreaker(threshold int, openTimeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		FailureThreshold: threshold,
+		OpenTimeout:      openTimeout,
+	}
+}
+
+// Breaker attaches a circuit breaker to the client, e.g.
+//
+//	cb := nxos.NewCircuitBreaker(5, 30*time.Second)
+//	cb.OnStateChange = func(from, to nxos.CircuitState) { log.Printf("circuit %s -> %s", from, to) }
+//	client, _ := nxos.NewClient("1.1.1.1", "user", "pwd", true, nxos.Breaker(cb))
+func Breaker(cb *CircuitBreaker) func(*Client) {
+	return func(client *Client) {
+		client.CircuitBreaker = cb
+	}
+}
+
+// State returns the current state of the circuit breaker.
+func (cb *CircuitBreaker) State() CircuitState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state
+}
+
+// Reset closes the circuit breaker and clears the failure count.
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.failures = 0
+	cb.probe = 0
+	cb.setState(CircuitClosed)
+}
+
+// allow reports whether a request may be sent, returning ErrCircuitOpen otherwise.
+// The returned ticket identifies the probe request in the half-open state and is 0
+// for requests in the closed state.
+func (cb *CircuitBreaker) allow(now time.Time) (uint64, error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch cb.state {
+	case CircuitOpen:
+		if now.Sub(cb.openedAt) < cb.OpenTimeout {
+			return 0, ErrCircuitOpen
+		}
+		cb.setState(CircuitHalfOpen)
+	case CircuitHalfOpen:
+		if cb.probe != 0 {
+			return 0, ErrCircuitOpen
+		}
+	default:
+		return 0, nil
+	}
+	cb.tickets++
+	cb.probe = cb.tickets
+	return cb.probe, nil
+}
+
+// record records the outcome of a request let through by allow with the given ticket.
+// While the circuit is not closed, only the outcome of the probe request changes the
+// state; outcomes of requests sent before the circuit opened are ignored.
+func (cb *CircuitBreaker) record(now time.Time, ticket uint64, failure bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state != CircuitClosed {
+		if ticket == 0 || ticket != cb.probe {
+			return
+		}
+		cb.probe = 0
+		if !failure {
+			cb.failures = 0
+			cb.setState(CircuitClosed)
+			return
+		}
+		cb.openedAt = now
+		cb.setState(CircuitOpen)
+		return
+	}
+	if ticket != 0 {
+		// Probe of a circuit reset in the meantime
+		return
+	}
+	if !failure {
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.failures >= cb.FailureThreshold {
+		cb.openedAt = now
+		cb.setState(CircuitOpen)
+	}
+}
+
+// cancel releases a request let through by allow without recording an outcome,
+// e.g. because the caller cancelled the request.
+func (cb *CircuitBreaker) cancel(ticket uint64) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if ticket != 0 && ticket == cb.probe {
+		cb.probe = 0
+	}
+}
+
+// setState transitions to the given state. The caller must hold the mutex.
+func (cb *CircuitBreaker) setState(state CircuitState) {
+	if cb.state == state {
+		return
+	}
+	from := cb.state
+	cb.state = state
+	if cb.OnStateChange != nil {
+		cb.OnStateChange(from, state)
+	}
+}
@@ -0,0 +1,146 @@
+package nxos
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// TestCircuitBreaker tests the CircuitBreaker state transitions.
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	cb := NewCircuitBreaker(2, time.Minute)
+	var transitions []string
+	cb.OnStateChange = func(from, to CircuitState) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	}
+
+	allow := func() uint64 {
+		ticket, err := cb.allow(now)
+		assert.NoError(t, err)
+		return ticket
+	}
+
+	cb.record(now, allow(), true)
+	assert.Equal(t, CircuitClosed, cb.State())
+	stale := allow()
+	cb.record(now, allow(), true)
+	assert.Equal(t, CircuitOpen, cb.State())
+	_, err := cb.allow(now)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// Results of requests sent before the circuit opened are ignored
+	cb.record(now, stale, false)
+	assert.Equal(t, CircuitOpen, cb.State())
+
+	// Single probe after timeout
+	now = now.Add(time.Minute)
+	probe := allow()
+	assert.NotZero(t, probe)
+	assert.Equal(t, CircuitHalfOpen, cb.State())
+	_, err = cb.allow(now)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	cb.record(now, stale, true)
+	assert.Equal(t, CircuitHalfOpen, cb.State())
+
+	// Failed probe opens the circuit again
+	cb.record(now, probe, true)
+	assert.Equal(t, CircuitOpen, cb.State())
+
+	// Cancelled probe allows another probe
+	now = now.Add(time.Minute)
+	cb.cancel(allow())
+	assert.Equal(t, CircuitHalfOpen, cb.State())
+
+	// Successful probe closes the circuit
+	cb.record(now, allow(), false)
+	assert.Equal(t, CircuitClosed, cb.State())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half-open",
+		"half-open->open",
+		"open->half-open",
+		"half-open->closed",
+	}, transitions)
+}
+
+// TestClientCircuitBreaker tests the circuit breaker integration of Client::Do.
+func TestClientCircuitBreaker(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	cb := NewCircuitBreaker(1, time.Hour)
+	Breaker(cb)(client)
+
+	gock.New(testURL).Get("/url.json").ReplyError(errors.New("fail"))
+	_, err := client.Get("/url")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, CircuitOpen, cb.State())
+
+	// Fail fast while open
+	_, err = client.Get("/url")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// Client errors close the circuit
+	cb.Reset()
+	gock.New(testURL).Get("/url.json").Reply(400)
+	_, err = client.Get("/url")
+	assert.NoError(t, err)
+	assert.Equal(t, CircuitClosed, cb.State())
+}
+
+// TestClientCircuitBreakerCommandErrors tests that failed CLI commands reported with a
+// server error status do not open the circuit.
+func TestClientCircuitBreakerCommandErrors(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	cb := NewCircuitBreaker(2, time.Hour)
+	Breaker(cb)(client)
+
+	gock.New(testURL).Post("/ins").Times(3).Reply(500).
+		BodyString(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`)
+	for i := 0; i < 3; i++ {
+		_, err := client.JsonRpc([]string{"show foo"})
+		var rpcErr *JsonRpcError
+		assert.ErrorAs(t, err, &rpcErr)
+	}
+	gock.New(testURL).Post("/ins").Times(3).Reply(500).
+		BodyString(`{"ins_api":{"outputs":{"output":{"input":"show foo","msg":"Input CLI command error","code":"400","clierror":"% Invalid command\n"}}}}`)
+	for i := 0; i < 3; i++ {
+		_, err := client.Cli([]string{"show foo"}, CliUsing(MethodCliShowAscii))
+		var insErr *InsApiError
+		assert.ErrorAs(t, err, &insErr)
+	}
+	assert.Equal(t, CircuitClosed, cb.State())
+	assert.True(t, gock.IsDone())
+
+	// Server errors without failed commands still open the circuit
+	gock.New(testURL).Post("/ins").Times(2).Reply(500)
+	client.JsonRpc([]string{"show version"}, NoRetry)
+	client.JsonRpc([]string{"show version"}, NoRetry)
+	assert.Equal(t, CircuitOpen, cb.State())
+}
+
+// TestClientCircuitBreakerRetry tests that a retry rejected by the circuit breaker
+// reports the error of the previous attempt.
+func TestClientCircuitBreakerRetry(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	Breaker(NewCircuitBreaker(1, time.Hour))(client)
+	Retry(RetryPolicyFunc(func(a RetryAttempt) (bool, time.Duration) {
+		return a.Attempt < 3, 0
+	}))(client)
+
+	gock.New(testURL).Get("/url.json").Reply(503)
+	_, err := client.Get("/url")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	var apiErr *APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, 503, apiErr.StatusCode)
+	}
+	assert.True(t, gock.IsDone())
+}
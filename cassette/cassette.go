@@ -0,0 +1,357 @@
+// Package cassette provides a recording and replaying http.RoundTripper for
+// testing code built on the nxos client without a device.
+//
+// In record mode, requests are forwarded to the device and the request/response
+// pairs are captured with credentials and tokens redacted. In replay mode,
+// responses are served from the cassette file, e.g.
+//
+//	client, _ := nxos.NewClient("https://10.0.0.1", "user", "pwd", true)
+//	rec, _ := cassette.New("testdata/interfaces.json", cassette.ModeReplay, client.HttpClient.Transport)
+//	client.HttpClient.Transport = rec
+//	defer rec.Save()
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Redacted replaces sensitive values in recorded interactions.
+const Redacted = "REDACTED"
+
+// ErrNoInteraction is returned in replay mode if no recorded interaction matches a request.
+var ErrNoInteraction = errors.New("no matching interaction in cassette")
+
+// Mode is the operating mode of a Recorder.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette without contacting the device.
+	ModeReplay Mode = iota
+	// ModeRecord forwards requests to the device and records the interactions.
+	ModeRecord
+)
+
+// Match selects the request properties used to match interactions in replay mode.
+type Match int
+
+const (
+	// MatchMethod matches the HTTP method.
+	MatchMethod Match = 1 << iota
+	// MatchPath matches the URL path.
+	MatchPath
+	// MatchQuery matches the URL query string.
+	MatchQuery
+	// MatchBody matches the request body. JSON bodies are compared after normalization.
+	MatchBody
+
+	// MatchAll matches all request properties.
+	MatchAll = MatchMethod | MatchPath | MatchQuery | MatchBody
+)
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is a recorded request/response pair.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper recording or replaying interactions.
+// Use cassette.New to create a recorder.
+type Recorder struct {
+	// Match selects the request properties used for matching in replay mode.
+	// Defaults to MatchAll.
+	Match Match
+	// SensitiveKeys lists JSON keys whose values are redacted, e.g. "pwd" and "token".
+	SensitiveKeys []string
+	// SensitiveHeaders lists HTTP headers whose values are redacted.
+	SensitiveHeaders []string
+
+	mode      Mode
+	path      string
+	transport http.RoundTripper
+	mu        sync.Mutex
+	cassette  Cassette
+	used      []bool
+}
+
+// New creates a new Recorder for the cassette file at path.
+// In replay mode the cassette file is loaded immediately. In record mode requests are
+// forwarded using transport, or http.DefaultTransport if nil.
+func New(path string, mode Mode, transport http.RoundTripper) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	r := &Recorder{
+		Match:            MatchAll,
+		SensitiveKeys:    []string{"pwd", "password", "token"},
+		SensitiveHeaders: []string{"Authorization", "Cookie", "Set-Cookie"},
+		mode:             mode,
+		path:             path,
+		transport:        transport,
+	}
+	if mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette: %w", err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Interactions returns the recorded or loaded interactions.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// Save writes the recorded interactions to the cassette file.
+// It is a no-op in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0o644)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recReq := Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+		Body:   r.redactBody(body),
+	}
+	if r.mode == ModeReplay {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return r.replay(req, recReq)
+	}
+	return r.record(req, recReq)
+}
+
+func (r *Recorder) record(req *http.Request, recReq Request) (*http.Response, error) {
+	res, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := res.Header.Clone()
+	for _, h := range r.SensitiveHeaders {
+		if header.Get(h) != "" {
+			header.Set(h, Redacted)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recReq,
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     header,
+			Body:       r.redactBody(body),
+		},
+	})
+	return res, nil
+}
+
+func (r *Recorder) replay(req *http.Request, recReq Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !r.matches(interaction.Request, recReq) {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+			StatusCode:    interaction.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        interaction.Response.Header.Clone(),
+			Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+			ContentLength: int64(len(interaction.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, recReq.Method, req.URL.RequestURI())
+}
+
+func (r *Recorder) matches(a, b Request) bool {
+	match := r.Match
+	if match == 0 {
+		match = MatchAll
+	}
+	if match&MatchMethod != 0 && a.Method != b.Method {
+		return false
+	}
+	if match&MatchPath != 0 && a.Path != b.Path {
+		return false
+	}
+	if match&MatchQuery != 0 && a.Query != b.Query {
+		return false
+	}
+	if match&MatchBody != 0 && normalize(a.Body) != normalize(b.Body) {
+		return false
+	}
+	return true
+}
+
+// redactBody replaces the values of sensitive JSON keys in place, keeping the
+// rest of the body unchanged. Non-JSON bodies are returned unchanged.
+func (r *Recorder) redactBody(body []byte) string {
+	if len(body) == 0 || !gjson.ValidBytes(body) {
+		return string(body)
+	}
+	redacted := string(body)
+	for _, path := range r.sensitivePaths(gjson.ParseBytes(body), "") {
+		redacted, _ = sjson.Set(redacted, path, Redacted)
+	}
+	return redacted
+}
+
+// sensitivePaths returns the SJSON paths of the values of sensitive keys.
+func (r *Recorder) sensitivePaths(v gjson.Result, prefix string) []string {
+	var paths []string
+	isArray := v.IsArray()
+	i := 0
+	v.ForEach(func(k, val gjson.Result) bool {
+		path := keyEscaper.Replace(k.Str)
+		if isArray {
+			path = fmt.Sprint(i)
+			i++
+		}
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		if !isArray && r.sensitive(k.Str) {
+			paths = append(paths, path)
+		} else if val.IsObject() || val.IsArray() {
+			paths = append(paths, r.sensitivePaths(val, path)...)
+		}
+		return true
+	})
+	return paths
+}
+
+var keyEscaper = strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`, "|", `\|`, "#", `\#`, "@", `\@`, ":", `\:`)
+
+func (r *Recorder) sensitive(key string) bool {
+	for _, k := range r.SensitiveKeys {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize returns the canonical form of a JSON body with sorted keys, keeping
+// numbers and characters as they are.
+func normalize(body string) string {
+	var v any
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	if dec.Decode(&v) != nil {
+		return body
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return body
+	}
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if enc.Encode(v) != nil {
+		return body
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// readBody reads the request body without modifying req, which a RoundTripper
+// must not do. The body is read from req.GetBody if available, otherwise it is
+// consumed and a clone of req with a copy of the body is returned.
+func readBody(req *http.Request) (*http.Request, []byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			req.Body.Close()
+			return nil, nil, err
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			req.Body.Close()
+			return nil, nil, err
+		}
+		return req, body, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return clone, body, nil
+}
@@ -0,0 +1,135 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/netascode/go-nxos"
+	"github.com/stretchr/testify/assert"
+)
+
+// testServer returns a server emulating the login and a single DN.
+func testServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/aaaLogin.json":
+			http.SetCookie(w, &http.Cookie{Name: "APIC-cookie", Value: "secret-token"})
+			io.WriteString(w, `{"imdata":[{"aaaLogin":{"attributes":{"token":"secret-token"}}}]}`)
+		case "/api/mo/sys/intf/phys-[eth1/1].json":
+			io.WriteString(w, `{"imdata":[{"l1PhysIf":{"attributes":{"id":"eth1/1","mtu":"9216"}}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// TestRecordReplay tests recording and replaying interactions.
+func TestRecordReplay(t *testing.T) {
+	srv := testServer()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	// Record
+	client, _ := nxos.NewClient(srv.URL, "admin", "my-password", true, nxos.MaxRetries(0))
+	rec, err := New(path, ModeRecord, client.HttpClient.Transport)
+	assert.NoError(t, err)
+	client.HttpClient.Transport = rec
+	res, err := client.GetDn("sys/intf/phys-[eth1/1]", nxos.Query("rsp-prop-include", "config-only"))
+	assert.NoError(t, err)
+	assert.Equal(t, "9216", res.Get("l1PhysIf.attributes.mtu").Str)
+	assert.NoError(t, rec.Save())
+	srv.Close()
+
+	// Credentials and tokens are redacted
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "my-password")
+	assert.NotContains(t, string(data), "secret-token")
+	assert.Len(t, rec.Interactions(), 2)
+
+	// Replay without device
+	client, _ = nxos.NewClient(srv.URL, "admin", "other-password", true, nxos.MaxRetries(0))
+	rec, err = New(path, ModeReplay, nil)
+	assert.NoError(t, err)
+	client.HttpClient.Transport = rec
+	res, err = client.GetDn("sys/intf/phys-[eth1/1]", nxos.Query("rsp-prop-include", "config-only"))
+	assert.NoError(t, err)
+	assert.Equal(t, "9216", res.Get("l1PhysIf.attributes.mtu").Str)
+	assert.Equal(t, Redacted, client.Token)
+
+	// Interactions are consumed in order
+	_, err = client.GetDn("sys/intf/phys-[eth1/1]", nxos.Query("rsp-prop-include", "config-only"))
+	assert.True(t, errors.Is(err, ErrNoInteraction))
+
+	// Query mismatch
+	rec, _ = New(path, ModeReplay, nil)
+	client.HttpClient.Transport = rec
+	_, err = client.GetDn("sys/intf/phys-[eth1/1]")
+	assert.True(t, errors.Is(err, ErrNoInteraction))
+
+	// Relaxed matching
+	rec, _ = New(path, ModeReplay, nil)
+	rec.Match = MatchMethod | MatchPath
+	client.HttpClient.Transport = rec
+	_, err = client.GetDn("sys/intf/phys-[eth1/1]")
+	assert.NoError(t, err)
+}
+
+// TestRedactBody tests that redaction keeps the body unchanged otherwise.
+func TestRedactBody(t *testing.T) {
+	rec, _ := New("", ModeRecord, nil)
+	body := `{"aaaUser":{"attributes":{"name":"admin","pwd":"secret","mtu":12345678901234567890,"descr":"a<b>&c"}},"list":[{"token":"t"},1.50]}`
+	assert.Equal(t,
+		`{"aaaUser":{"attributes":{"name":"admin","pwd":"REDACTED","mtu":12345678901234567890,"descr":"a<b>&c"}},"list":[{"token":"REDACTED"},1.50]}`,
+		rec.redactBody([]byte(body)))
+	assert.Equal(t, "not json", rec.redactBody([]byte("not json")))
+
+	// Normalized bodies keep numbers and characters
+	assert.Equal(t, `{"a":12345678901234567890,"b":"<&>"}`, normalize(`{"b": "<&>", "a": 12345678901234567890}`))
+	assert.Equal(t, `{} x`, normalize(`{} x`))
+}
+
+// transportFunc is an http.RoundTripper calling a function.
+type transportFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f transportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestRecordRequestBody tests that recording does not modify the request.
+func TestRecordRequestBody(t *testing.T) {
+	var sent []string
+	rec, err := New(filepath.Join(t.TempDir(), "cassette.json"), ModeRecord, transportFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body.Close()
+		sent = append(sent, string(body))
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}, nil
+	}))
+	assert.NoError(t, err)
+
+	// Body with GetBody
+	req, _ := http.NewRequest(http.MethodPost, "https://switch/api/mo/sys.json", strings.NewReader(`{"a":1}`))
+	orig := req.Body
+	_, err = rec.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.True(t, orig == req.Body)
+
+	// Body without GetBody
+	req, _ = http.NewRequest(http.MethodPost, "https://switch/api/mo/sys.json", io.MultiReader(strings.NewReader(`{"b":2}`)))
+	orig = req.Body
+	_, err = rec.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.True(t, orig == req.Body)
+
+	assert.Equal(t, []string{`{"a":1}`, `{"b":2}`}, sent)
+	if assert.Len(t, rec.Interactions(), 2) {
+		assert.Equal(t, `{"a":1}`, rec.Interactions()[0].Request.Body)
+		assert.Equal(t, `{"b":2}`, rec.Interactions()[1].Request.Body)
+	}
+}
@@ -0,0 +1,293 @@
+package nxos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RollbackMode is the behaviour of a rollback to a checkpoint if applying a command fails.
+type RollbackMode string
+
+const (
+	// RollbackAtomic only applies the rollback if all commands succeed.
+	RollbackAtomic RollbackMode = "atomic"
+	// RollbackBestEffort applies the rollback, skipping failed commands.
+	RollbackBestEffort RollbackMode = "best-effort"
+	// RollbackStopAtFirstFailure applies the rollback until the first failed command.
+	RollbackStopAtFirstFailure RollbackMode = "stop-at-first-failure"
+)
+
+// CheckpointTimeLayout is the layout of checkpoint creation times, e.g. "Wed, 19:37:03 13 Mar 2019"
+// or "Fri, 10:00:00 5 Apr 2019".
+const CheckpointTimeLayout = "Mon, 15:04:05 _2 Jan 2006"
+
+// checkpointNameRegexp matches valid checkpoint names.
+var checkpointNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Checkpoint is a configuration checkpoint as shown by "show checkpoint summary".
+type Checkpoint struct {
+	// Name is the name of the checkpoint.
+	Name string
+	// System is true for checkpoints created by the system, e.g. when disabling a feature.
+	System bool
+	// CreatedBy is the user which created the checkpoint.
+	CreatedBy string
+	// CreatedAt is the creation time. The device does not show its time zone, so the
+	// time is returned as UTC.
+	CreatedAt time.Time
+	// Size is the size of the checkpoint in bytes.
+	Size int
+	// Description is the description, empty if none.
+	Description string
+}
+
+// RollbackResult is the result of a rollback to a checkpoint.
+type RollbackResult struct {
+	// Checkpoint is the name of the checkpoint.
+	Checkpoint string
+	// Mode is the rollback mode.
+	Mode RollbackMode
+	// Output is the output of the rollback command.
+	Output string
+}
+
+// CreateCheckpoint creates a configuration checkpoint of the running configuration.
+// The description is optional. Names may only contain letters, digits, "_", "." and "-",
+// and descriptions must not contain ";" or line breaks.
+func (client *Client) CreateCheckpoint(name, description string, mods ...func(*Req)) error {
+	return client.CreateCheckpointCtx(context.Background(), name, description, mods...)
+}
+
+// CreateCheckpointCtx creates a configuration checkpoint using the given context.
+func (client *Client) CreateCheckpointCtx(ctx context.Context, name, description string, mods ...func(*Req)) error {
+	if err := checkCheckpointName(name); err != nil {
+		return err
+	}
+	if strings.ContainsAny(description, ";\r\n") {
+		return fmt.Errorf("invalid checkpoint description %q", description)
+	}
+	cmd := "checkpoint " + name
+	if description != "" {
+		cmd += " description " + description
+	}
+	_, err := client.cliAscii(ctx, cmd, mods...)
+	return err
+}
+
+// DeleteCheckpoint deletes a configuration checkpoint.
+func (client *Client) DeleteCheckpoint(name string, mods ...func(*Req)) error {
+	return client.DeleteCheckpointCtx(context.Background(), name, mods...)
+}
+
+// DeleteCheckpointCtx deletes a configuration checkpoint using the given context.
+func (client *Client) DeleteCheckpointCtx(ctx context.Context, name string, mods ...func(*Req)) error {
+	if err := checkCheckpointName(name); err != nil {
+		return err
+	}
+	_, err := client.cliAscii(ctx, "no checkpoint "+name, mods...)
+	return err
+}
+
+// ListCheckpoints returns all user and system checkpoints.
+func (client *Client) ListCheckpoints(mods ...func(*Req)) ([]Checkpoint, error) {
+	return client.ListCheckpointsCtx(context.Background(), mods...)
+}
+
+// ListCheckpointsCtx returns all user and system checkpoints using the given context.
+func (client *Client) ListCheckpointsCtx(ctx context.Context, mods ...func(*Req)) ([]Checkpoint, error) {
+	out, err := client.cliAscii(ctx, "show checkpoint summary", mods...)
+	if err != nil {
+		return nil, err
+	}
+	return parseCheckpoints(out)
+}
+
+// RollbackTo rolls back the running configuration to a checkpoint.
+// An error is returned if the device does not report a successful rollback; the
+// result contains the output of the rollback in any case.
+func (client *Client) RollbackTo(name string, mode RollbackMode, mods ...func(*Req)) (RollbackResult, error) {
+	return client.RollbackToCtx(context.Background(), name, mode, mods...)
+}
+
+// RollbackToCtx rolls back the running configuration to a checkpoint using the given context.
+// See RollbackTo for details.
+func (client *Client) RollbackToCtx(ctx context.Context, name string, mode RollbackMode, mods ...func(*Req)) (RollbackResult, error) {
+	if mode == "" {
+		mode = RollbackAtomic
+	}
+	result := RollbackResult{Checkpoint: name, Mode: mode}
+	if err := checkCheckpointName(name); err != nil {
+		return result, err
+	}
+	out, err := client.cliAscii(ctx, fmt.Sprintf("rollback running-config checkpoint %s %s", name, mode), mods...)
+	result.Output = out
+	if err != nil {
+		return result, fmt.Errorf("rollback to checkpoint %s failed: %w", name, err)
+	}
+	if !strings.Contains(strings.ToLower(out), "completed successfully") {
+		return result, fmt.Errorf("rollback to checkpoint %s failed: %s", name, strings.TrimSpace(out))
+	}
+	return result, nil
+}
+
+// CheckpointOptions are the options of WithCheckpoint.
+type CheckpointOptions struct {
+	// Name is the name of the checkpoint, defaults to nxos-<unix time in nanoseconds>.
+	Name string
+	// Mode is the rollback mode, defaults to RollbackAtomic.
+	Mode RollbackMode
+	// Keep keeps the checkpoint after the transaction.
+	Keep bool
+	// Mods are request modifiers applied to all checkpoint requests.
+	Mods []func(*Req)
+}
+
+// CheckpointName sets the name of the checkpoint created by WithCheckpoint.
+func CheckpointName(name string) func(*CheckpointOptions) {
+	return func(o *CheckpointOptions) {
+		o.Name = name
+	}
+}
+
+// CheckpointRollbackMode sets the rollback mode used by WithCheckpoint.
+func CheckpointRollbackMode(mode RollbackMode) func(*CheckpointOptions) {
+	return func(o *CheckpointOptions) {
+		o.Mode = mode
+	}
+}
+
+// KeepCheckpoint keeps the checkpoint created by WithCheckpoint instead of deleting it.
+func KeepCheckpoint() func(*CheckpointOptions) {
+	return func(o *CheckpointOptions) {
+		o.Keep = true
+	}
+}
+
+// CheckpointReqMods sets request modifiers used for all checkpoint requests of WithCheckpoint.
+func CheckpointReqMods(mods ...func(*Req)) func(*CheckpointOptions) {
+	return func(o *CheckpointOptions) {
+		o.Mods = append(o.Mods, mods...)
+	}
+}
+
+// WithCheckpoint creates a checkpoint, runs fn and rolls back to the checkpoint if fn
+// returns an error or panics, e.g.
+//
+//	err := client.WithCheckpoint(func() error {
+//	    if _, err := client.Post("sys/intf/phys-[eth1/1]", body); err != nil {
+//	        return err
+//	    }
+//	    _, err := client.JsonRpc([]string{"conf t", "interface eth1/2", "shutdown"})
+//	    return err
+//	})
+//
+// The returned error wraps the error of fn and, if the rollback failed, the rollback error.
+// The checkpoint is deleted afterwards unless KeepCheckpoint is passed.
+func (client *Client) WithCheckpoint(fn func() error, opts ...func(*CheckpointOptions)) error {
+	return client.WithCheckpointCtx(context.Background(), fn, opts...)
+}
+
+// WithCheckpointCtx runs fn within a checkpoint using the given context for checkpoint requests.
+// See WithCheckpoint for details.
+func (client *Client) WithCheckpointCtx(ctx context.Context, fn func() error, opts ...func(*CheckpointOptions)) (err error) {
+	o := CheckpointOptions{Mode: RollbackAtomic}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Name == "" {
+		o.Name = fmt.Sprintf("nxos-%d", client.clock().Now().UnixNano())
+	}
+	if err := client.CreateCheckpointCtx(ctx, o.Name, "", o.Mods...); err != nil {
+		return fmt.Errorf("cannot create checkpoint %s: %w", o.Name, err)
+	}
+	defer func() {
+		// Roll back and clean up even if ctx is cancelled
+		ctx := context.WithoutCancel(ctx)
+		p := recover()
+		if p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+		if err != nil {
+			if _, rbErr := client.RollbackToCtx(ctx, o.Name, o.Mode, o.Mods...); rbErr != nil {
+				err = errors.Join(err, rbErr)
+			} else {
+				err = fmt.Errorf("rolled back to checkpoint %s: %w", o.Name, err)
+			}
+		}
+		if !o.Keep {
+			if delErr := client.DeleteCheckpointCtx(ctx, o.Name, o.Mods...); delErr != nil && err == nil {
+				err = fmt.Errorf("cannot delete checkpoint %s: %w", o.Name, delErr)
+			}
+		}
+		if p != nil {
+			panic(p)
+		}
+	}()
+	return fn()
+}
+
+// cliAscii runs a single command using the cli_ascii method and returns its output.
+func (client *Client) cliAscii(ctx context.Context, cmd string, mods ...func(*Req)) (string, error) {
+	results, err := client.CliCtx(ctx, []string{cmd}, CliUsing(MethodCliAscii), CliReqMods(mods...))
+	if err != nil || len(results) == 0 {
+		return "", err
+	}
+	return results[0].Text, nil
+}
+
+// checkCheckpointName returns an error if a checkpoint name is invalid, e.g. because
+// it contains spaces which would change the checkpoint commands.
+func checkCheckpointName(name string) error {
+	if !checkpointNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid checkpoint name %q", name)
+	}
+	return nil
+}
+
+// parseCheckpoints parses the output of "show checkpoint summary", e.g.
+//
+//	User Checkpoint Summary
+//	--------------------------------------------------------------------------------
+//	1) cp1:
+//	Created by admin
+//	Created at Wed, 19:37:03 13 Mar 2019
+//	Size is 46,548 bytes
+//	Description: None
+func parseCheckpoints(out string) ([]Checkpoint, error) {
+	var checkpoints []Checkpoint
+	var cp *Checkpoint
+	system := false
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasSuffix(line, "Checkpoint Summary"):
+			system = strings.HasPrefix(line, "System")
+		case strings.HasSuffix(line, ":") && strings.Contains(line, ") "):
+			_, name, _ := strings.Cut(strings.TrimSuffix(line, ":"), ") ")
+			checkpoints = append(checkpoints, Checkpoint{Name: name, System: system})
+			cp = &checkpoints[len(checkpoints)-1]
+		case cp == nil:
+		case strings.HasPrefix(line, "Created by "):
+			cp.CreatedBy = strings.TrimPrefix(line, "Created by ")
+		case strings.HasPrefix(line, "Created at "):
+			t, err := time.Parse(CheckpointTimeLayout, strings.TrimPrefix(line, "Created at "))
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse creation time of checkpoint %s: %w", cp.Name, err)
+			}
+			cp.CreatedAt = t
+		case strings.HasPrefix(line, "Size is "):
+			size := strings.TrimSuffix(strings.TrimPrefix(line, "Size is "), " bytes")
+			cp.Size, _ = strconv.Atoi(strings.ReplaceAll(size, ",", ""))
+		case strings.HasPrefix(line, "Description:"):
+			if d := strings.TrimSpace(strings.TrimPrefix(line, "Description:")); d != "None" {
+				cp.Description = d
+			}
+		}
+	}
+	return checkpoints, nil
+}
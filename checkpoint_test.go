@@ -0,0 +1,164 @@
+package nxos
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const testCheckpointSummary = `User Checkpoint Summary
+--------------------------------------------------------------------------------
+1) cp1:
+Created by admin
+Created at Wed, 19:37:03 13 Mar 2019
+Size is 46,548 bytes
+Description: None
+
+2) cp2:
+Created by automation
+Created at Thu, 08:01:00 14 Mar 2019
+Size is 46,600 bytes
+Description: before upgrade
+
+System Checkpoint Summary
+--------------------------------------------------------------------------------
+3) system-fm-vrrp:
+Created by admin
+Created at Fri, 10:00:00 5 Apr 2019
+Size is 40,000 bytes
+Description: Created by Feature Manager.
+`
+
+func cliAsciiReply(msg string) string {
+	return Body{}.Set("jsonrpc", "2.0").SetRaw("id", "1").Set("result.msg", msg).Str
+}
+
+// TestClientCheckpoints tests creating, listing and deleting checkpoints.
+func TestClientCheckpoints(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/ins").
+		BodyString(`"method":"cli_ascii","params":{"cmd":"checkpoint cp2 description before upgrade"`).
+		Reply(200).BodyString(cliAsciiReply("Done\n"))
+	assert.NoError(t, client.CreateCheckpoint("cp2", "before upgrade"))
+
+	gock.New(testURL).Post("/ins").Reply(200).BodyString(cliAsciiReply(testCheckpointSummary))
+	cps, err := client.ListCheckpoints()
+	assert.NoError(t, err)
+	assert.Len(t, cps, 3)
+	assert.Equal(t, Checkpoint{
+		Name:      "cp1",
+		CreatedBy: "admin",
+		CreatedAt: time.Date(2019, 3, 13, 19, 37, 3, 0, time.UTC),
+		Size:      46548,
+	}, cps[0])
+	assert.Equal(t, "before upgrade", cps[1].Description)
+	assert.True(t, cps[2].System)
+	assert.Equal(t, "system-fm-vrrp", cps[2].Name)
+	assert.Equal(t, time.Date(2019, 4, 5, 10, 0, 0, 0, time.UTC), cps[2].CreatedAt)
+
+	// Unknown time format
+	gock.New(testURL).Post("/ins").Reply(200).BodyString(cliAsciiReply("1) cp1:\nCreated at 2019-03-13 19:37:03\n"))
+	_, err = client.ListCheckpoints()
+	assert.ErrorContains(t, err, "cannot parse creation time of checkpoint cp1")
+
+	// Invalid names and descriptions are rejected before sending
+	assert.ErrorContains(t, client.CreateCheckpoint("cp 1", ""), "invalid checkpoint name")
+	assert.ErrorContains(t, client.CreateCheckpoint("cp1", "a ; reload"), "invalid checkpoint description")
+	assert.ErrorContains(t, client.DeleteCheckpoint("cp1;reload"), "invalid checkpoint name")
+	_, err = client.RollbackTo("", RollbackAtomic)
+	assert.ErrorContains(t, err, "invalid checkpoint name")
+	_, err = client.CommitConfirm(time.Minute, func() error { t.Fail(); return nil }, CommitName("cc 1"))
+	assert.ErrorContains(t, err, "invalid checkpoint name")
+
+	gock.New(testURL).Post("/ins").
+		BodyString(`"cmd":"no checkpoint cp2"`).
+		Reply(200).BodyString(cliAsciiReply(""))
+	assert.NoError(t, client.DeleteCheckpoint("cp2"))
+
+	// Command error
+	gock.New(testURL).Post("/ins").Reply(200).
+		BodyString(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params","data":{"msg":"Checkpoint name already exists\n"}}}`)
+	err = client.CreateCheckpoint("cp1", "")
+	assert.ErrorContains(t, err, "Invalid params: Checkpoint name already exists")
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientRollbackTo tests the Client::RollbackTo method.
+func TestClientRollbackTo(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/ins").
+		BodyString(`"cmd":"rollback running-config checkpoint cp1 best-effort"`).
+		Reply(200).BodyString(cliAsciiReply("Collecting Running-Config\nExecuting Rollback Patch\nRollback completed successfully.\n"))
+	res, err := client.RollbackTo("cp1", RollbackBestEffort)
+	assert.NoError(t, err)
+	assert.Equal(t, RollbackBestEffort, res.Mode)
+	assert.Contains(t, res.Output, "Executing Rollback Patch")
+
+	gock.New(testURL).Post("/ins").
+		BodyString(`"cmd":"rollback running-config checkpoint cp1 atomic"`).
+		Reply(200).BodyString(cliAsciiReply("Executing Rollback Patch\nRollback failed.\n"))
+	res, err = client.RollbackTo("cp1", "")
+	assert.ErrorContains(t, err, "Rollback failed.")
+	assert.Equal(t, RollbackAtomic, res.Mode)
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientWithCheckpoint tests the Client::WithCheckpoint method.
+func TestClientWithCheckpoint(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// Success
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint tx1"`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no checkpoint tx1"`).Reply(200).BodyString(cliAsciiReply(""))
+	called := false
+	err := client.WithCheckpoint(func() error {
+		called = true
+		return nil
+	}, CheckpointName("tx1"))
+	assert.NoError(t, err)
+	assert.True(t, called)
+	assert.True(t, gock.IsDone())
+
+	// Failure rolls back
+	fail := errors.New("fail")
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint tx2"`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"rollback running-config checkpoint tx2 stop-at-first-failure"`).
+		Reply(200).BodyString(cliAsciiReply("Rollback completed successfully.\n"))
+	err = client.WithCheckpoint(func() error { return fail },
+		CheckpointName("tx2"), CheckpointRollbackMode(RollbackStopAtFirstFailure), KeepCheckpoint())
+	assert.ErrorIs(t, err, fail)
+	assert.ErrorContains(t, err, "rolled back to checkpoint tx2")
+	assert.True(t, gock.IsDone())
+
+	// Failed rollback
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint tx3"`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"rollback`).Reply(200).BodyString(cliAsciiReply("Rollback failed.\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no checkpoint tx3"`).Reply(200).BodyString(cliAsciiReply(""))
+	err = client.WithCheckpoint(func() error { return fail }, CheckpointName("tx3"))
+	assert.ErrorIs(t, err, fail)
+	assert.ErrorContains(t, err, "rollback to checkpoint tx3 failed")
+	assert.True(t, gock.IsDone())
+
+	// Panic rolls back and re-panics
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint tx4"`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"rollback`).Reply(200).BodyString(cliAsciiReply("Rollback completed successfully.\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no checkpoint tx4"`).Reply(200).BodyString(cliAsciiReply(""))
+	assert.PanicsWithValue(t, "boom", func() {
+		client.WithCheckpoint(func() error { panic("boom") }, CheckpointName("tx4"))
+	})
+	assert.True(t, gock.IsDone())
+
+	// Checkpoint creation fails
+	gock.New(testURL).Post("/ins").Reply(200).
+		BodyString(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`)
+	err = client.WithCheckpoint(func() error { t.Fail(); return nil }, CheckpointName("tx5"))
+	assert.ErrorContains(t, err, "cannot create checkpoint tx5")
+}
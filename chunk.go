@@ -0,0 +1,149 @@
+package nxos
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ChunkProgress is the progress of a chunked retrieval, reported after every chunk.
+type ChunkProgress struct {
+	// Command is the command.
+	Command string
+	// Chunks is the number of chunks received so far.
+	Chunks int
+	// Bytes is the number of bytes received so far.
+	Bytes int64
+	// Done is true after the last chunk.
+	Done bool
+}
+
+// ChunkOptions are the options of ShowChunked and ShowChunkedTo.
+type ChunkOptions struct {
+	// Method is the ins_api method, i.e. MethodCliShowAscii (default) or MethodCliShow.
+	Method CliMethod
+	// Progress is called after every chunk.
+	Progress func(ChunkProgress)
+	// Mods are request modifiers applied to all chunk requests.
+	Mods []func(*Req)
+}
+
+// ChunkMethod sets the ins_api method used to retrieve chunks, i.e. MethodCliShowAscii
+// for text output or MethodCliShow for JSON output.
+func ChunkMethod(method CliMethod) func(*ChunkOptions) {
+	return func(o *ChunkOptions) {
+		o.Method = method
+	}
+}
+
+// ChunkProgressFunc sets a function called after every chunk.
+func ChunkProgressFunc(fn func(ChunkProgress)) func(*ChunkOptions) {
+	return func(o *ChunkOptions) {
+		o.Progress = fn
+	}
+}
+
+// ChunkReqMods sets request modifiers used for all chunk requests.
+func ChunkReqMods(mods ...func(*Req)) func(*ChunkOptions) {
+	return func(o *ChunkOptions) {
+		o.Mods = append(o.Mods, mods...)
+	}
+}
+
+// ShowChunked runs a show command in NX-API chunk mode and reassembles the chunks,
+// e.g. for output exceeding the limit of a single response:
+//
+//	result, err := client.ShowChunked("show running-config")
+//	fmt.Print(result.Text)
+//
+// With MethodCliShow, the reassembled JSON output is returned in Body.
+func (client *Client) ShowChunked(command string, opts ...func(*ChunkOptions)) (CliResult, error) {
+	return client.ShowChunkedCtx(context.Background(), command, opts...)
+}
+
+// ShowChunkedCtx runs a show command in chunk mode using the given context.
+// See ShowChunked for details.
+func (client *Client) ShowChunkedCtx(ctx context.Context, command string, opts ...func(*ChunkOptions)) (CliResult, error) {
+	var out strings.Builder
+	if _, err := client.ShowChunkedToCtx(ctx, &out, command, opts...); err != nil {
+		return CliResult{}, err
+	}
+	result := CliResult{Command: command, Text: out.String(), Sid: "eoc"}
+	o := chunkOptions(opts)
+	if o.Method == MethodCliShow {
+		if !gjson.Valid(result.Text) {
+			return CliResult{}, fmt.Errorf("invalid JSON output of %q", command)
+		}
+		result.Body, result.Text = gjson.Parse(result.Text), ""
+	}
+	return result, nil
+}
+
+// ShowChunkedTo runs a show command in NX-API chunk mode and writes the chunks to w
+// as they are received, e.g. to stream large output to a file:
+//
+//	f, _ := os.Create("running-config.txt")
+//	defer f.Close()
+//	n, err := client.ShowChunkedTo(f, "show running-config",
+//	    nxos.ChunkProgressFunc(func(p nxos.ChunkProgress) {
+//	        log.Printf("%d chunks, %d bytes", p.Chunks, p.Bytes)
+//	    }))
+//
+// It returns the number of bytes written.
+func (client *Client) ShowChunkedTo(w io.Writer, command string, opts ...func(*ChunkOptions)) (int64, error) {
+	return client.ShowChunkedToCtx(context.Background(), w, command, opts...)
+}
+
+// ShowChunkedToCtx runs a show command in chunk mode using the given context and writes the chunks to w.
+// See ShowChunkedTo for details.
+func (client *Client) ShowChunkedToCtx(ctx context.Context, w io.Writer, command string, opts ...func(*ChunkOptions)) (int64, error) {
+	o := chunkOptions(opts)
+	if o.Method != MethodCliShow && o.Method != MethodCliShowAscii {
+		return 0, fmt.Errorf("method %s does not support chunk mode: %w", o.Method, ErrInvalidArgument)
+	}
+	progress := ChunkProgress{Command: command}
+	sid := ""
+	for {
+		results, err := client.CliCtx(ctx, []string{command}, CliUsing(o.Method), CliChunk(sid), CliReqMods(o.Mods...))
+		if err != nil {
+			return progress.Bytes, err
+		}
+		if len(results) != 1 {
+			return progress.Bytes, fmt.Errorf("invalid chunk of %q: %d outputs", command, len(results))
+		}
+		// A device returning the requested session id again does not advance
+		if sid != "" && results[0].Sid == sid {
+			return progress.Bytes, fmt.Errorf("invalid chunk of %q: session %s repeated", command, sid)
+		}
+		chunk := results[0].Text
+		if chunk == "" && results[0].Body.Exists() {
+			// Output returned without chunking
+			chunk = results[0].Body.Raw
+		}
+		n, err := io.WriteString(w, chunk)
+		progress.Bytes += int64(n)
+		if err != nil {
+			return progress.Bytes, err
+		}
+		progress.Chunks++
+		sid = results[0].Sid
+		progress.Done = sid == "" || sid == "eoc"
+		if o.Progress != nil {
+			o.Progress(progress)
+		}
+		if progress.Done {
+			return progress.Bytes, nil
+		}
+	}
+}
+
+func chunkOptions(opts []func(*ChunkOptions)) ChunkOptions {
+	o := ChunkOptions{Method: MethodCliShowAscii}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
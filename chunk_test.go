@@ -0,0 +1,67 @@
+package nxos
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// chunkReply returns an ins_api response with a chunk of output.
+func chunkReply(sid, body string) string {
+	return Body{}.
+		Set("ins_api.sid", sid).
+		Set("ins_api.outputs.output.input", "show run").
+		Set("ins_api.outputs.output.code", "200").
+		Set("ins_api.outputs.output.msg", "Success").
+		Set("ins_api.outputs.output.body", body).Str
+}
+
+// TestClientShowChunked tests the Client::ShowChunked method.
+func TestClientShowChunked(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/ins").BodyString(`"chunk":"1","sid":"1"`).Reply(200).BodyString(chunkReply("s1", "version 10.3\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"chunk":"1","sid":"s1"`).Reply(200).BodyString(chunkReply("s1b", "hostname n9k\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"chunk":"1","sid":"s1b"`).Reply(200).BodyString(chunkReply("eoc", "end\n"))
+	var progress []ChunkProgress
+	result, err := client.ShowChunked("show run", ChunkProgressFunc(func(p ChunkProgress) {
+		progress = append(progress, p)
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "version 10.3\nhostname n9k\nend\n", result.Text)
+	assert.Equal(t, []ChunkProgress{
+		{Command: "show run", Chunks: 1, Bytes: 13},
+		{Command: "show run", Chunks: 2, Bytes: 26},
+		{Command: "show run", Chunks: 3, Bytes: 30, Done: true},
+	}, progress)
+
+	// JSON output
+	gock.New(testURL).Post("/ins").BodyString(`"type":"cli_show","chunk":"1"`).Reply(200).BodyString(chunkReply("s2", `{"TABLE_vrf":{"ROW_vrf":`))
+	gock.New(testURL).Post("/ins").BodyString(`"sid":"s2"`).Reply(200).BodyString(chunkReply("eoc", `{"vrf-name-out":"default"}}}`))
+	result, err = client.ShowChunked("show ip route", ChunkMethod(MethodCliShow))
+	assert.NoError(t, err)
+	assert.Equal(t, "default", result.Body.Get("TABLE_vrf.ROW_vrf.vrf-name-out").Str)
+
+	// Repeated session id
+	gock.New(testURL).Post("/ins").BodyString(`"chunk":"1","sid":"1"`).Reply(200).BodyString(chunkReply("s3", "version 10.3\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"chunk":"1","sid":"s3"`).Reply(200).BodyString(chunkReply("s3", "version 10.3\n"))
+	n, err := client.ShowChunkedTo(io.Discard, "show run")
+	assert.ErrorContains(t, err, "session s3 repeated")
+	assert.Equal(t, int64(13), n)
+
+	// Failed command
+	gock.New(testURL).Post("/ins").
+		Reply(200).
+		BodyString(`{"ins_api":{"sid":"eoc","outputs":{"output":{"input":"show foo","msg":"Input CLI command error","code":"400"}}}}`)
+	var out strings.Builder
+	_, err = client.ShowChunkedTo(&out, "show foo")
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	_, err = client.ShowChunkedTo(&out, "show run", ChunkMethod(MethodCliConf))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	assert.True(t, gock.IsDone())
+}
@@ -0,0 +1,333 @@
+package nxos
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CliMethod is the NX-API method used to run CLI commands.
+type CliMethod string
+
+const (
+	// MethodCli runs commands with JSON-RPC and returns structured output.
+	MethodCli CliMethod = "cli"
+	// MethodCliAscii runs commands with JSON-RPC and returns text output.
+	MethodCliAscii CliMethod = "cli_ascii"
+	// MethodCliShow runs show commands with an ins_api message and returns structured output.
+	MethodCliShow CliMethod = "cli_show"
+	// MethodCliShowAscii runs show commands with an ins_api message and returns text output.
+	MethodCliShowAscii CliMethod = "cli_show_ascii"
+	// MethodCliConf runs configuration commands with an ins_api message.
+	MethodCliConf CliMethod = "cli_conf"
+	// MethodBash runs bash commands with an ins_api message. Requires "feature bash-shell".
+	MethodBash CliMethod = "bash"
+)
+
+// OutputFormat is the message and output format of ins_api requests.
+type OutputFormat string
+
+const (
+	// OutputJSON sends JSON messages and returns JSON output.
+	OutputJSON OutputFormat = "json"
+	// OutputXML sends XML messages and returns XML output.
+	OutputXML OutputFormat = "xml"
+)
+
+// CliResult is the output of a single CLI command, independent of the NX-API method.
+type CliResult struct {
+	// Command is the command.
+	Command string
+	// Body is the structured output, i.e. JSON output of MethodCli and MethodCliShow.
+	Body Res
+	// Text is the text output, i.e. output of MethodCliAscii, MethodCliShowAscii and
+	// MethodBash, XML output, or a chunk of the output in chunk mode.
+	Text string
+	// Sid is the session id of an ins_api response, used to request the next chunk
+	// with CliChunk. It is "eoc" if the output is complete.
+	Sid string
+	// Err is the error of the command, i.e. a *JsonRpcError or *InsApiError, or nil.
+	Err error
+}
+
+// InsApiError is the error of a failed command of an ins_api request.
+type InsApiError struct {
+	// Command is the failed command.
+	Command string
+	// Code is the ins_api status code, e.g. "400".
+	Code string
+	// Message is the status message, e.g. "Input CLI command error".
+	Message string
+	// CliError is the error output of the command, e.g. "% Invalid command\n".
+	CliError string
+}
+
+func (e *InsApiError) Error() string {
+	msg := e.Message
+	if cliErr := strings.TrimSpace(e.CliError); cliErr != "" {
+		msg += ": " + cliErr
+	}
+	return fmt.Sprintf("command %q failed: %s", e.Command, msg)
+}
+
+// Is reports whether the error matches one of the sentinel errors.
+func (e *InsApiError) Is(target error) bool {
+	switch target {
+	case ErrFeatureNotEnabled:
+		return isFeatureNotEnabledText(e.Message + " " + e.CliError)
+	case ErrInvalidArgument:
+		return e.Code == "400"
+	}
+	return false
+}
+
+// CliOptions are the options of Cli.
+type CliOptions struct {
+	// Method is the NX-API method, defaults to MethodCli.
+	Method CliMethod
+	// Format is the message and output format of ins_api methods, defaults to OutputJSON.
+	Format OutputFormat
+	// Chunk enables chunk mode of ins_api methods, returning large output in chunks.
+	Chunk bool
+	// Sid is the session id of the chunk to request, empty for the first chunk.
+	Sid string
+	// Mods are request modifiers applied to the request.
+	Mods []func(*Req)
+}
+
+// CliUsing sets the NX-API method used by Cli.
+func CliUsing(method CliMethod) func(*CliOptions) {
+	return func(o *CliOptions) {
+		o.Method = method
+	}
+}
+
+// CliOutputFormat sets the message and output format of ins_api methods.
+func CliOutputFormat(format OutputFormat) func(*CliOptions) {
+	return func(o *CliOptions) {
+		o.Format = format
+	}
+}
+
+// CliChunk enables chunk mode of ins_api methods. The sid is the session id returned
+// with the previous chunk, or empty for the first chunk.
+func CliChunk(sid string) func(*CliOptions) {
+	return func(o *CliOptions) {
+		o.Chunk = true
+		o.Sid = sid
+	}
+}
+
+// CliReqMods sets request modifiers used for the request of Cli.
+func CliReqMods(mods ...func(*Req)) func(*CliOptions) {
+	return func(o *CliOptions) {
+		o.Mods = append(o.Mods, mods...)
+	}
+}
+
+// Cli runs CLI commands and returns a result per command. The NX-API method is selected
+// with CliUsing, e.g. text output of a show command with an ins_api message:
+//
+//	results, err := client.Cli([]string{"show clock"}, nxos.CliUsing(nxos.MethodCliShowAscii))
+//	fmt.Print(results[0].Text)
+//
+// If a command fails, the results are returned together with the error of the first
+// failed command, i.e. a *JsonRpcError or *InsApiError.
+func (client *Client) Cli(commands []string, opts ...func(*CliOptions)) ([]CliResult, error) {
+	return client.CliCtx(context.Background(), commands, opts...)
+}
+
+// CliCtx runs CLI commands using the given context.
+// See Cli for details.
+func (client *Client) CliCtx(ctx context.Context, commands []string, opts ...func(*CliOptions)) ([]CliResult, error) {
+	o := CliOptions{Method: MethodCli, Format: OutputJSON}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	switch o.Method {
+	case MethodCli, MethodCliAscii:
+		if o.Format != OutputJSON || o.Chunk {
+			return nil, fmt.Errorf("method %s does not support %s output or chunk mode: %w", o.Method, o.Format, ErrInvalidArgument)
+		}
+		res, err := client.jsonRpc(ctx, string(o.Method), commands, o.Mods...)
+		var results []CliResult
+		for _, r := range jsonRpcResults(res, commands) {
+			result := CliResult{Command: r.Command, Body: r.Body, Text: r.Msg}
+			if r.Error != nil {
+				result.Err = r.Error
+			}
+			results = append(results, result)
+		}
+		return results, err
+	case MethodCliShow, MethodCliShowAscii, MethodCliConf, MethodBash:
+		if o.Format != OutputJSON && o.Format != OutputXML {
+			return nil, fmt.Errorf("unknown output format %q: %w", o.Format, ErrInvalidArgument)
+		}
+		res, err := client.insApi(ctx, o, commands)
+		if err != nil {
+			return nil, err
+		}
+		results, err := insApiResults(res, commands)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			if result.Err != nil {
+				return results, result.Err
+			}
+		}
+		return results, nil
+	}
+	return nil, fmt.Errorf("unknown CLI method %q: %w", o.Method, ErrInvalidArgument)
+}
+
+// insApiRequest is the ins_api message of a request.
+type insApiRequest struct {
+	XMLName      xml.Name `xml:"ins_api"`
+	Version      string   `xml:"version"`
+	Type         string   `xml:"type"`
+	Chunk        string   `xml:"chunk"`
+	Sid          string   `xml:"sid"`
+	Input        string   `xml:"input"`
+	OutputFormat string   `xml:"output_format"`
+}
+
+// insApiResponse is the ins_api message of an XML response.
+type insApiResponse struct {
+	Sid     string `xml:"sid"`
+	Outputs []struct {
+		Input    string `xml:"input"`
+		Msg      string `xml:"msg"`
+		Code     string `xml:"code"`
+		CliError string `xml:"clierror"`
+		Body     struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"body"`
+	} `xml:"outputs>output"`
+}
+
+// insApi makes an ins_api request. XML responses are returned as a string value.
+func (client *Client) insApi(ctx context.Context, o CliOptions, commands []string) (_ Res, err error) {
+	ctx, span := client.tracer().Start(ctx, "nxos.InsApi",
+		trace.WithAttributes(attribute.Int(AttrCommands, len(commands))))
+	defer func() { endSpan(span, err) }()
+
+	msg := insApiRequest{
+		Version:      "1.0",
+		Type:         string(o.Method),
+		Chunk:        "0",
+		Sid:          o.Sid,
+		Input:        strings.Join(commands, " ;"),
+		OutputFormat: string(o.Format),
+	}
+	if o.Chunk {
+		msg.Chunk = "1"
+	}
+	if msg.Sid == "" {
+		msg.Sid = "1"
+	}
+	var data, contentType string
+	if o.Format == OutputXML {
+		b, _ := xml.Marshal(msg)
+		data, contentType = xml.Header+string(b), "application/xml"
+	} else {
+		data = Body{}.
+			Set("ins_api.version", msg.Version).
+			Set("ins_api.type", msg.Type).
+			Set("ins_api.chunk", msg.Chunk).
+			Set("ins_api.sid", msg.Sid).
+			Set("ins_api.input", msg.Input).
+			Set("ins_api.output_format", msg.OutputFormat).Str
+		contentType = "application/json"
+	}
+	req := client.NewReqCtx(ctx, "POST", "/ins", strings.NewReader(data), o.Mods...)
+	req.HttpReq.Header.Add("Content-Type", contentType)
+	req.HttpReq.Header.Add("Cache-Control", "no-cache")
+	req.HttpReq.SetBasicAuth(client.Usr, client.Pwd)
+	client.cliRequest(&req, hasInsApiError)
+	res, err := client.DoCtx(ctx, req)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		// Failed commands may be reported with a server error status
+		if raw := gjson.Parse(apiErr.Raw); hasInsApiError(raw) {
+			res, err = raw, nil
+		}
+	}
+	return res, err
+}
+
+// hasInsApiError reports whether an ins_api response contains a failed command.
+func hasInsApiError(res Res) bool {
+	results, err := insApiResults(res, nil)
+	if err != nil {
+		return false
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// insApiResults converts the outputs of an ins_api response to results.
+func insApiResults(res Res, commands []string) ([]CliResult, error) {
+	if res.Type == gjson.String {
+		return insApiXMLResults(res.Str, commands)
+	}
+	ins := res.Get("ins_api")
+	if !ins.Exists() {
+		return nil, fmt.Errorf("invalid ins_api response: %s", res.Raw)
+	}
+	outputs := ins.Get("outputs.output")
+	entries := outputs.Array()
+	var results []CliResult
+	for i, out := range entries {
+		result := CliResult{Command: out.Get("input").Str, Sid: ins.Get("sid").Str}
+		if result.Command == "" && i < len(commands) {
+			result.Command = commands[i]
+		}
+		if body := out.Get("body"); body.Type == gjson.String {
+			result.Text = body.Str
+		} else {
+			result.Body = body
+		}
+		if code := out.Get("code").Str; code != "" && code != "200" {
+			result.Err = &InsApiError{
+				Command:  result.Command,
+				Code:     code,
+				Message:  out.Get("msg").Str,
+				CliError: out.Get("clierror").Str,
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// insApiXMLResults converts the outputs of an ins_api XML response to results.
+// The body of each output is returned as text.
+func insApiXMLResults(doc string, commands []string) ([]CliResult, error) {
+	var msg insApiResponse
+	if err := xml.Unmarshal([]byte(doc), &msg); err != nil {
+		return nil, fmt.Errorf("invalid ins_api response: %w", err)
+	}
+	var results []CliResult
+	for i, out := range msg.Outputs {
+		result := CliResult{Command: out.Input, Text: out.Body.Inner, Sid: msg.Sid}
+		if result.Command == "" && i < len(commands) {
+			result.Command = commands[i]
+		}
+		if out.Code != "" && out.Code != "200" {
+			result.Err = &InsApiError{Command: result.Command, Code: out.Code, Message: out.Msg, CliError: out.CliError}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
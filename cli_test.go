@@ -0,0 +1,108 @@
+package nxos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const testInsApiShow = `{
+  "ins_api": {
+    "type": "cli_show",
+    "version": "1.0",
+    "sid": "eoc",
+    "outputs": {
+      "output": [
+        {"input": "show version", "msg": "Success", "code": "200", "body": {"nxos_ver_str": "10.3(2)"}},
+        {"input": "show foo", "msg": "Input CLI command error", "code": "400", "clierror": "% Invalid command\n"}
+      ]
+    }
+  }
+}`
+
+const testInsApiXML = `<?xml version="1.0"?>
+<ins_api>
+  <type>cli_show</type>
+  <version>1.0</version>
+  <sid>eoc</sid>
+  <outputs>
+    <output>
+      <body><nxos_ver_str>10.3(2)</nxos_ver_str></body>
+      <input>show version</input>
+      <msg>Success</msg>
+      <code>200</code>
+    </output>
+  </outputs>
+</ins_api>`
+
+// TestClientCli tests the Client::Cli method.
+func TestClientCli(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// JSON-RPC text output
+	gock.New(testURL).Post("/ins").
+		BodyString(`"method":"cli_ascii"`).
+		Reply(200).
+		BodyString(cliAsciiReply("Wed Mar 13 19:37:03 2019\n"))
+	results, err := client.Cli([]string{"show clock"}, CliUsing(MethodCliAscii))
+	assert.NoError(t, err)
+	assert.Equal(t, []CliResult{{Command: "show clock", Text: "Wed Mar 13 19:37:03 2019\n"}}, results)
+
+	// ins_api structured output
+	gock.New(testURL).Post("/ins").
+		BodyString(`"type":"cli_show","chunk":"0","sid":"1","input":"show version ;show foo","output_format":"json"`).
+		Reply(200).
+		BodyString(testInsApiShow)
+	results, err = client.Cli([]string{"show version", "show foo"}, CliUsing(MethodCliShow))
+	assert.EqualError(t, err, `command "show foo" failed: Input CLI command error: % Invalid command`)
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "10.3(2)", results[0].Body.Get("nxos_ver_str").Str)
+	assert.Equal(t, "eoc", results[0].Sid)
+	assert.NoError(t, results[0].Err)
+	var insErr *InsApiError
+	assert.ErrorAs(t, results[1].Err, &insErr)
+	assert.Equal(t, "400", insErr.Code)
+
+	// ins_api text output
+	gock.New(testURL).Post("/ins").
+		BodyString(`"type":"cli_show_ascii"`).
+		Reply(200).
+		BodyString(`{"ins_api":{"sid":"eoc","outputs":{"output":{"input":"show clock","msg":"Success","code":"200","body":"19:37:03\n"}}}}`)
+	results, err = client.Cli([]string{"show clock"}, CliUsing(MethodCliShowAscii))
+	assert.NoError(t, err)
+	assert.Equal(t, []CliResult{{Command: "show clock", Text: "19:37:03\n", Sid: "eoc"}}, results)
+
+	// ins_api XML output
+	gock.New(testURL).Post("/ins").
+		MatchHeader("Content-Type", "application/xml").
+		Reply(200).
+		SetHeader("Content-Type", "text/xml").
+		BodyString(testInsApiXML)
+	results, err = client.Cli([]string{"show version"}, CliUsing(MethodCliShow), CliOutputFormat(OutputXML))
+	assert.NoError(t, err)
+	assert.Equal(t, []CliResult{{Command: "show version", Text: "<nxos_ver_str>10.3(2)</nxos_ver_str>", Sid: "eoc"}}, results)
+
+	// Chunk mode
+	gock.New(testURL).Post("/ins").
+		BodyString(`"chunk":"1","sid":"sid1"`).
+		Reply(200).
+		BodyString(`{"ins_api":{"sid":"sid2","outputs":{"output":{"input":"show run","msg":"Success","code":"200","body":"version 10.3\n"}}}}`)
+	results, err = client.Cli([]string{"show run"}, CliUsing(MethodCliShowAscii), CliChunk("sid1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "sid2", results[0].Sid)
+
+	// Failed command reported with a server error
+	gock.New(testURL).Post("/ins").Reply(500).BodyString(testInsApiShow)
+	_, err = client.Cli([]string{"show version", "show foo"}, CliUsing(MethodCliShow))
+	assert.ErrorAs(t, err, &insErr)
+
+	// Invalid options
+	_, err = client.Cli([]string{"show version"}, CliOutputFormat(OutputXML))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, err = client.Cli([]string{"show version"}, CliUsing("cli_foo"))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	assert.True(t, gock.IsDone())
+}
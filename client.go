@@ -3,12 +3,13 @@ package nxos
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
-	"math"
-	"math/rand"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	"strings"
@@ -16,20 +17,10 @@ import (
 	"time"
 
 	"github.com/tidwall/gjson"
-	"github.com/tidwall/sjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// AuthenticationError indicates that the device was reachable but
-// rejected the supplied credentials (HTTP 401/403).
-type AuthenticationError struct {
-	StatusCode int
-	Message    string
-}
-
-func (e *AuthenticationError) Error() string {
-	return fmt.Sprintf("authentication failed (HTTP %d): %s", e.StatusCode, e.Message)
-}
-
 const DefaultMaxRetries int = 3
 const DefaultBackoffMinDelay int = 4
 const DefaultBackoffMaxDelay int = 60
@@ -61,8 +52,32 @@ type Client struct {
 	BackoffMaxDelay int
 	// Backoff delay factor
 	BackoffDelayFactor float64
+	// RetryPolicy decides whether failed requests are retried.
+	// If nil, an ExponentialBackoff policy derived from the fields above is used.
+	RetryPolicy RetryPolicy
+	// Clock is used for retry timing. If nil, the real clock is used.
+	Clock Clock
+	// CircuitBreaker optionally rejects requests after consecutive failures.
+	CircuitBreaker *CircuitBreaker
+	// Metrics optionally receives request, retry and authentication metrics.
+	Metrics MetricsSink
+	// Tracer optionally creates OpenTelemetry spans, see TracerProvider.
+	Tracer trace.Tracer
+	// Middleware wraps every call to Do, see Middlewares.
+	Middleware []Middleware
+	// AttemptMiddleware wraps every HTTP attempt of Do, see AttemptMiddlewares.
+	AttemptMiddleware []AttemptMiddleware
+	// Logger is the structured logger used for request, response and retry logging.
+	Logger *slog.Logger
 	// Mutex for authentication token refresh
 	authMutex sync.Mutex
+	// Rate limiter applied to every request attempt
+	rateLimiter *tokenBucket
+	// Semaphore limiting the number of concurrent requests
+	concurrency chan struct{}
+	limiterCounters
+	// Error of an invalid client modifier, returned by NewClient
+	modErr error
 }
 
 // NewClient creates a new NXOS HTTP client.
@@ -99,6 +114,9 @@ func NewClient(url, usr, pwd string, insecure bool, mods ...func(*Client)) (*Cli
 	for _, mod := range mods {
 		mod(client)
 	}
+	if client.modErr != nil {
+		return nil, client.modErr
+	}
 	return client, nil
 }
 
@@ -139,7 +157,12 @@ func BackoffDelayFactor(x float64) func(*Client) {
 
 // NewReq creates a new Req request for this client.
 func (client *Client) NewReq(method, uri string, body io.Reader, mods ...func(*Req)) Req {
-	httpReq, _ := http.NewRequest(method, client.Url+uri+".json", body)
+	return client.NewReqCtx(context.Background(), method, uri, body, mods...)
+}
+
+// NewReqCtx creates a new Req request for this client bound to the given context.
+func (client *Client) NewReqCtx(ctx context.Context, method, uri string, body io.Reader, mods ...func(*Req)) Req {
+	httpReq, _ := http.NewRequestWithContext(ctx, method, client.Url+uri+".json", body)
 	req := Req{
 		HttpReq:    httpReq,
 		Refresh:    true,
@@ -157,72 +180,234 @@ func (client *Client) NewReq(method, uri string, body io.Reader, mods ...func(*R
 //	req := client.NewReq("GET", "/api/mo/sys/bgp", nil)
 //	res, _ := client.Do(req)
 func (client *Client) Do(req Req) (Res, error) {
+	return client.DoCtx(req.HttpReq.Context(), req)
+}
+
+// DoCtx makes a request using the given context.
+// Cancelling the context aborts the current HTTP request as well as any pending
+// retries and backoff delays. The returned error then wraps ctx.Err(), e.g.
+//
+//	res, err := client.DoCtx(ctx, req)
+//	if errors.Is(err, context.Canceled) {
+//	    ...
+//	}
+func (client *Client) DoCtx(ctx context.Context, req Req) (Res, error) {
+	if req.Err != nil {
+		return Res{}, req.Err
+	}
+	handler := Handler(client.do)
+	for i := len(client.Middleware) - 1; i >= 0; i-- {
+		handler = client.Middleware[i](handler)
+	}
+	return handler(ctx, req)
+}
+
+// do executes a request including retries. It is the innermost Handler of DoCtx.
+func (client *Client) do(ctx context.Context, req Req) (_ Res, err error) {
+	tracer := client.tracer()
+	ctx, span := tracer.Start(ctx, "nxos.Do",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.HttpReq.Method),
+			attribute.String("url.full", req.HttpReq.URL.String()),
+			attribute.String(AttrDn, dnFromPath(req.HttpReq.URL.Path)),
+			attribute.String(AttrAPI, apiType(req.HttpReq.URL.Path)),
+		))
+	defer func() { endSpan(span, err) }()
+
+	req.HttpReq = req.HttpReq.WithContext(ctx)
+	attemptHandler := AttemptHandler(client.attempt)
+	for i := len(client.AttemptMiddleware) - 1; i >= 0; i-- {
+		attemptHandler = client.AttemptMiddleware[i](attemptHandler)
+	}
+	logger := client.logger().With(
+		slog.String("device", client.Url),
+		slog.String("method", req.HttpReq.Method),
+		slog.String("path", req.HttpReq.URL.Path),
+	)
+	policy := client.retryPolicy(req)
+	clock := client.clock()
+	labels := MetricLabels{
+		Device: client.Url,
+		Method: req.HttpReq.Method,
+		API:    apiType(req.HttpReq.URL.Path),
+	}
+
 	// retain the request body across multiple attempts
 	var body []byte
 	if req.HttpReq.Body != nil {
 		body, _ = io.ReadAll(req.HttpReq.Body)
 	}
 
-	var res Res
-
+	start := clock.Now()
+	// error of the previous attempt, reported along with a circuit breaker rejection of the retry
+	var lastErr error
 	for attempts := 0; ; attempts++ {
+		if err := ctx.Err(); err != nil {
+			logger.LogAttrs(ctx, slog.LevelDebug, "Exit from Do method, context done", slog.Any("error", err))
+			return Res{}, contextError(err)
+		}
+
 		req.HttpReq.Body = io.NopCloser(bytes.NewBuffer(body))
-		if req.LogPayload {
-			log.Printf("[DEBUG] HTTP Request: %s, %s, %s", req.HttpReq.Method, req.HttpReq.URL, gjson.Parse(string(body)).Get("@pretty"))
-		} else {
-			log.Printf("[DEBUG] HTTP Request: %s, %s", req.HttpReq.Method, req.HttpReq.URL)
+		if client.debugEnabled(ctx) {
+			if req.LogPayload {
+				logger.LogAttrs(ctx, slog.LevelDebug, "HTTP Request",
+					slog.Int("attempt", attempts),
+					slog.String("query", req.HttpReq.URL.RawQuery),
+					slog.String("payload", gjson.Parse(string(body)).Get("@pretty").String()))
+			} else {
+				logger.LogAttrs(ctx, slog.LevelDebug, "HTTP Request",
+					slog.Int("attempt", attempts),
+					slog.String("query", req.HttpReq.URL.RawQuery))
+			}
 		}
 
-		httpRes, err := client.HttpClient.Do(req.HttpReq)
-		if err != nil {
-			if ok := client.Backoff(attempts); !ok {
-				log.Printf("[ERROR] HTTP Connection error occured: %+v", err)
-				log.Printf("[DEBUG] Exit from Do method")
+		var ticket uint64
+		if cb := client.CircuitBreaker; cb != nil {
+			t, err := cb.allow(clock.Now())
+			if err != nil {
+				logger.LogAttrs(ctx, slog.LevelError, "Request rejected by circuit breaker", slog.Int("attempt", attempts))
+				err = fmt.Errorf("%w: %s", err, client.Url)
+				if lastErr != nil {
+					return Res{}, errors.Join(lastErr, err)
+				}
 				return Res{}, err
-			} else {
-				log.Printf("[ERROR] HTTP Connection failed: %s, retries: %v", err, attempts)
-				continue
 			}
+			ticket = t
 		}
 
-		bodyBytes, err := io.ReadAll(httpRes.Body)
-		httpRes.Body.Close()
+		release, err := client.acquire(ctx, labels)
 		if err != nil {
-			if ok := client.Backoff(attempts); !ok {
-				log.Printf("[ERROR] Cannot decode response body: %+v", err)
-				log.Printf("[DEBUG] Exit from Do method")
-				return Res{}, err
-			} else {
-				log.Printf("[ERROR] Cannot decode response body: %s, retries: %v", err, attempts)
-				continue
+			if cb := client.CircuitBreaker; cb != nil {
+				cb.cancel(ticket)
+			}
+			logger.LogAttrs(ctx, slog.LevelDebug, "Exit from Do method, context done", slog.Any("error", err))
+			return Res{}, contextError(err)
+		}
+
+		attemptCtx, attemptSpan := tracer.Start(ctx, "nxos.attempt",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.Int(AttrAttempt, attempts)))
+		req.HttpReq = req.HttpReq.WithContext(attemptCtx)
+		attemptStart := clock.Now()
+		result, err := attemptHandler(attemptCtx, req)
+		release()
+		if err == nil {
+			attemptSpan.SetAttributes(attribute.Int("http.response.status_code", result.StatusCode))
+		}
+		endSpan(attemptSpan, err)
+		if client.Metrics != nil {
+			client.Metrics.ObserveRequest(labels, result.StatusCode, err, clock.Now().Sub(attemptStart))
+		}
+		if err != nil && ctx.Err() != nil {
+			if cb := client.CircuitBreaker; cb != nil {
+				cb.cancel(ticket)
 			}
+			logger.LogAttrs(ctx, slog.LevelDebug, "Exit from Do method, context done", slog.Any("error", ctx.Err()))
+			return Res{}, contextError(ctx.Err())
 		}
-		res = Res(gjson.ParseBytes(bodyBytes))
-		if req.LogPayload {
-			log.Printf("[DEBUG] HTTP Response: %s", gjson.Parse(res.Raw).Get("@pretty"))
+		if cb := client.CircuitBreaker; cb != nil {
+			failed := err != nil || (result.StatusCode >= 500 && result.StatusCode <= 504 &&
+				(req.commandFailed == nil || !req.commandFailed(result.Res)))
+			cb.record(clock.Now(), ticket, failed)
 		}
 
-		if (httpRes.StatusCode < 500 || httpRes.StatusCode > 504) && httpRes.StatusCode != 405 {
-			log.Printf("[DEBUG] Exit from Do method")
-			break
-		} else {
-			if ok := client.Backoff(attempts); !ok {
-				log.Printf("[ERROR] HTTP Request failed: StatusCode %v", httpRes.StatusCode)
-				log.Printf("[DEBUG] Exit from Do method")
-				return Res{}, fmt.Errorf("HTTP Request failed: StatusCode %v", httpRes.StatusCode)
-			} else {
-				log.Printf("[ERROR] HTTP Request failed: StatusCode %v, Retries: %v", httpRes.StatusCode, attempts)
-				continue
+		attempt := RetryAttempt{
+			Attempt: attempts,
+			Method:  req.HttpReq.Method,
+			Path:    req.HttpReq.URL.Path,
+			Err:     err,
+		}
+		res := result.Res
+		if err == nil {
+			if client.debugEnabled(ctx) {
+				attrs := []slog.Attr{
+					slog.Int("attempt", attempts),
+					slog.Int("status", result.StatusCode),
+					slog.Duration("latency", clock.Now().Sub(attemptStart)),
+				}
+				if req.LogPayload {
+					attrs = append(attrs, slog.String("payload", res.Get("@pretty").String()))
+				}
+				logger.LogAttrs(ctx, slog.LevelDebug, "HTTP Response", attrs...)
+			}
+
+			attempt.StatusCode = result.StatusCode
+			attempt.Code = res.Get("imdata.0.error.attributes.code").Str
+			span.SetAttributes(
+				attribute.Int("http.response.status_code", attempt.StatusCode),
+				attribute.Int(AttrAttempt, attempts))
+			if attempt.Code != "" {
+				span.SetAttributes(attribute.String(AttrErrorCode, attempt.Code))
+			}
+			if !isRetryableStatus(attempt.StatusCode) && attempt.Code == "" {
+				return res, nil
+			}
+			attempt.Err = newAPIError(attempt.StatusCode, req.HttpReq.Method, req.HttpReq.URL.Path, res)
+			attempt.RetryAfter = parseRetryAfter(result.Header.Get("Retry-After"), clock.Now())
+		}
+		attempt.Elapsed = clock.Now().Sub(start)
+
+		retry, delay := policy.Retry(attempt)
+		if !retry {
+			logger.LogAttrs(ctx, slog.LevelError, "HTTP Request failed",
+				slog.Int("attempt", attempts),
+				slog.Int("status", attempt.StatusCode),
+				slog.Any("error", attempt.Err))
+			if attempt.StatusCode == 0 || isRetryableStatus(attempt.StatusCode) {
+				return Res{}, attempt.Err
 			}
+			return res, attempt.Err
+		}
+		logger.LogAttrs(ctx, slog.LevelError, "HTTP Request failed, retrying",
+			slog.Int("attempt", attempts),
+			slog.Int("status", attempt.StatusCode),
+			slog.Duration("delay", delay),
+			slog.Any("error", attempt.Err))
+		if client.Metrics != nil {
+			client.Metrics.ObserveRetry(labels, delay)
+		}
+		lastErr = attempt.Err
+		_, backoffSpan := tracer.Start(ctx, "nxos.backoff",
+			trace.WithAttributes(attribute.Int64(AttrDelay, delay.Milliseconds())))
+		err = clock.Sleep(ctx, delay)
+		endSpan(backoffSpan, err)
+		if err != nil {
+			logger.LogAttrs(ctx, slog.LevelDebug, "Exit from Do method, context done", slog.Any("error", err))
+			return Res{}, contextError(err)
 		}
 	}
+}
 
-	errCode := res.Get("imdata.0.error.attributes.code").Str
-	if errCode != "" {
-		log.Printf("[ERROR] JSON error: %s", res.Raw)
-		return res, fmt.Errorf("JSON error: %s", res.Raw)
+// attempt performs a single HTTP attempt of a request. It is the innermost
+// AttemptHandler of Do.
+func (client *Client) attempt(ctx context.Context, req Req) (AttemptResult, error) {
+	httpRes, err := client.HttpClient.Do(req.HttpReq)
+	if err != nil {
+		return AttemptResult{}, err
+	}
+	defer httpRes.Body.Close()
+	bodyBytes, err := io.ReadAll(httpRes.Body)
+	if err != nil {
+		return AttemptResult{}, fmt.Errorf("cannot decode response body: %w", err)
 	}
-	return res, nil
+	res := Res(gjson.ParseBytes(bodyBytes))
+	if strings.Contains(httpRes.Header.Get("Content-Type"), "xml") {
+		// Keep XML documents, e.g. ins_api XML responses, as a string value
+		raw, _ := json.Marshal(string(bodyBytes))
+		res = Res(gjson.ParseBytes(raw))
+	}
+	return AttemptResult{
+		StatusCode: httpRes.StatusCode,
+		Header:     httpRes.Header,
+		Res:        res,
+	}, nil
+}
+
+// contextError wraps a context error so that callers can distinguish a
+// cancelled or expired request from a device failure using errors.Is.
+func contextError(err error) error {
+	return fmt.Errorf("request aborted: %w", err)
 }
 
 // Get makes a GET request and returns a GJSON result.
@@ -243,9 +428,20 @@ func (client *Client) Do(req Req) (Res, error) {
 //	  ]
 //	}
 func (client *Client) Get(path string, mods ...func(*Req)) (Res, error) {
-	req := client.NewReq("GET", path, nil, mods...)
-	client.Authenticate()
-	return client.Do(req)
+	return client.GetCtx(context.Background(), path, mods...)
+}
+
+// GetCtx makes a GET request using the given context.
+// See Get for details.
+func (client *Client) GetCtx(ctx context.Context, path string, mods ...func(*Req)) (Res, error) {
+	req := client.NewReqCtx(ctx, "GET", path, nil, mods...)
+	if req.Err != nil {
+		return Res{}, req.Err
+	}
+	if req.Refresh {
+		client.AuthenticateCtx(ctx)
+	}
+	return client.DoCtx(ctx, req)
 }
 
 // GetClass makes a GET request by class and unwraps the results.
@@ -262,7 +458,13 @@ func (client *Client) Get(path string, mods ...func(*Req)) (Res, error) {
 //	  }
 //	]
 func (client *Client) GetClass(class string, mods ...func(*Req)) (Res, error) {
-	res, err := client.Get(fmt.Sprintf("/api/class/%s", class), mods...)
+	return client.GetClassCtx(context.Background(), class, mods...)
+}
+
+// GetClassCtx makes a GET request by class using the given context.
+// See GetClass for details.
+func (client *Client) GetClassCtx(ctx context.Context, class string, mods ...func(*Req)) (Res, error) {
+	res, err := client.GetCtx(ctx, fmt.Sprintf("/api/class/%s", class), mods...)
 	if err != nil {
 		return res, err
 	}
@@ -281,7 +483,13 @@ func (client *Client) GetClass(class string, mods ...func(*Req)) (Res, error) {
 //	  }
 //	}
 func (client *Client) GetDn(dn string, mods ...func(*Req)) (Res, error) {
-	res, err := client.Get(fmt.Sprintf("/api/mo/%s", dn), mods...)
+	return client.GetDnCtx(context.Background(), dn, mods...)
+}
+
+// GetDnCtx makes a GET request by DN using the given context.
+// See GetDn for details.
+func (client *Client) GetDnCtx(ctx context.Context, dn string, mods ...func(*Req)) (Res, error) {
+	res, err := client.GetCtx(ctx, fmt.Sprintf("/api/mo/%s", escapeDn(dn)), mods...)
 	if err != nil {
 		return res, err
 	}
@@ -290,55 +498,127 @@ func (client *Client) GetDn(dn string, mods ...func(*Req)) (Res, error) {
 
 // DeleteDn makes a DELETE request by DN.
 func (client *Client) DeleteDn(dn string, mods ...func(*Req)) (Res, error) {
-	req := client.NewReq("DELETE", fmt.Sprintf("/api/mo/%s", dn), nil, mods...)
-	client.Authenticate()
-	return client.Do(req)
+	return client.DeleteDnCtx(context.Background(), dn, mods...)
+}
+
+// DeleteDnCtx makes a DELETE request by DN using the given context.
+func (client *Client) DeleteDnCtx(ctx context.Context, dn string, mods ...func(*Req)) (Res, error) {
+	req := client.NewReqCtx(ctx, "DELETE", fmt.Sprintf("/api/mo/%s", escapeDn(dn)), nil, mods...)
+	if req.Err != nil {
+		return Res{}, req.Err
+	}
+	if req.Refresh {
+		client.AuthenticateCtx(ctx)
+	}
+	return client.DoCtx(ctx, req)
 }
 
 // Post makes a POST request and returns a GJSON result.
 // Hint: Use the Body struct to easily create POST body data.
 func (client *Client) Post(dn, data string, mods ...func(*Req)) (Res, error) {
-	req := client.NewReq("POST", fmt.Sprintf("/api/mo/%s", dn), strings.NewReader(data), mods...)
-	client.Authenticate()
-	return client.Do(req)
+	return client.PostCtx(context.Background(), dn, data, mods...)
+}
+
+// PostCtx makes a POST request using the given context.
+func (client *Client) PostCtx(ctx context.Context, dn, data string, mods ...func(*Req)) (Res, error) {
+	req := client.NewReqCtx(ctx, "POST", fmt.Sprintf("/api/mo/%s", escapeDn(dn)), strings.NewReader(data), mods...)
+	if req.Err != nil {
+		return Res{}, req.Err
+	}
+	if req.Refresh {
+		client.AuthenticateCtx(ctx)
+	}
+	return client.DoCtx(ctx, req)
 }
 
 // Put makes a PUT request and returns a GJSON result.
 // Hint: Use the Body struct to easily create PUT body data.
 func (client *Client) Put(dn, data string, mods ...func(*Req)) (Res, error) {
-	req := client.NewReq("PUT", fmt.Sprintf("/api/mo/%s", dn), strings.NewReader(data), mods...)
-	client.Authenticate()
-	return client.Do(req)
+	return client.PutCtx(context.Background(), dn, data, mods...)
 }
 
-// JsonRpc makes a JSON-RPC request with one or more commands and returns a GJSON result.
-func (client *Client) JsonRpc(commands []string, mods ...func(*Req)) (Res, error) {
-	data := "[]"
-	for i, cmd := range commands {
-		prefix := fmt.Sprintf("%d", i)
-		data, _ = sjson.Set(data, prefix+".jsonrpc", "2.0")
-		data, _ = sjson.Set(data, prefix+".method", "cli")
-		data, _ = sjson.Set(data, prefix+".params.cmd", cmd)
-		data, _ = sjson.Set(data, prefix+".params.version", 1)
-		data, _ = sjson.Set(data, prefix+".id", i+1)
+// PutCtx makes a PUT request using the given context.
+func (client *Client) PutCtx(ctx context.Context, dn, data string, mods ...func(*Req)) (Res, error) {
+	req := client.NewReqCtx(ctx, "PUT", fmt.Sprintf("/api/mo/%s", escapeDn(dn)), strings.NewReader(data), mods...)
+	if req.Err != nil {
+		return Res{}, req.Err
 	}
-	req := client.NewReq("POST", "/ins", strings.NewReader(data), mods...)
-	req.HttpReq.Header.Add("Content-Type", "application/json-rpc")
-	req.HttpReq.Header.Add("Cache-Control", "no-cache")
-	req.HttpReq.SetBasicAuth(client.Usr, client.Pwd)
-	return client.Do(req)
+	if req.Refresh {
+		client.AuthenticateCtx(ctx)
+	}
+	return client.DoCtx(ctx, req)
+}
+
+// GetMo makes a GET request by a typed DN, e.g.
+//
+//	res, _ := client.GetMo(nxos.NewDn("sys", "intf").Child(nxos.NewRn("phys", "eth1/1")))
+//
+// See GetDn for details.
+func (client *Client) GetMo(dn Dn, mods ...func(*Req)) (Res, error) {
+	return client.GetDnCtx(context.Background(), string(dn), mods...)
+}
+
+// GetMoCtx makes a GET request by a typed DN using the given context.
+func (client *Client) GetMoCtx(ctx context.Context, dn Dn, mods ...func(*Req)) (Res, error) {
+	return client.GetDnCtx(ctx, string(dn), mods...)
+}
+
+// DeleteMo makes a DELETE request by a typed DN.
+func (client *Client) DeleteMo(dn Dn, mods ...func(*Req)) (Res, error) {
+	return client.DeleteDnCtx(context.Background(), string(dn), mods...)
+}
+
+// DeleteMoCtx makes a DELETE request by a typed DN using the given context.
+func (client *Client) DeleteMoCtx(ctx context.Context, dn Dn, mods ...func(*Req)) (Res, error) {
+	return client.DeleteDnCtx(ctx, string(dn), mods...)
+}
+
+// PostMo makes a POST request by a typed DN.
+func (client *Client) PostMo(dn Dn, data string, mods ...func(*Req)) (Res, error) {
+	return client.PostCtx(context.Background(), string(dn), data, mods...)
+}
+
+// PostMoCtx makes a POST request by a typed DN using the given context.
+func (client *Client) PostMoCtx(ctx context.Context, dn Dn, data string, mods ...func(*Req)) (Res, error) {
+	return client.PostCtx(ctx, string(dn), data, mods...)
+}
+
+// PutMo makes a PUT request by a typed DN.
+func (client *Client) PutMo(dn Dn, data string, mods ...func(*Req)) (Res, error) {
+	return client.PutCtx(context.Background(), string(dn), data, mods...)
+}
+
+// PutMoCtx makes a PUT request by a typed DN using the given context.
+func (client *Client) PutMoCtx(ctx context.Context, dn Dn, data string, mods ...func(*Req)) (Res, error) {
+	return client.PutCtx(ctx, string(dn), data, mods...)
 }
 
 // Login authenticates to the NXOS device.
 func (client *Client) Login() error {
+	return client.LoginCtx(context.Background())
+}
+
+// LoginCtx authenticates to the NXOS device using the given context.
+func (client *Client) LoginCtx(ctx context.Context) (err error) {
+	ctx, span := client.tracer().Start(ctx, "nxos.Login")
+	defer func() { endSpan(span, err) }()
+	if client.Metrics != nil {
+		start := client.clock().Now()
+		defer func() {
+			client.Metrics.ObserveAuth(client.Url, "login", err, client.clock().Now().Sub(start))
+		}()
+	}
 	data := fmt.Sprintf(`{"aaaUser":{"attributes":{"name":"%s","pwd":"%s"}}}`,
 		client.Usr,
 		client.Pwd,
 	)
-	req := client.NewReq("POST", "/api/aaaLogin", strings.NewReader(data), NoRefresh, NoLogPayload)
+	req := client.NewReqCtx(ctx, "POST", "/api/aaaLogin", strings.NewReader(data), NoRefresh, NoLogPayload)
 
 	httpRes, err := client.HttpClient.Do(req.HttpReq)
 	if err != nil {
+		if ctx.Err() != nil {
+			return contextError(ctx.Err())
+		}
 		return err
 	}
 
@@ -379,7 +659,20 @@ func (client *Client) Login() error {
 // Refresh will be checked every request and the token will be refreshed after 8 minutes.
 // Pass nxos.NoRefresh to prevent automatic refresh handling and handle it directly instead.
 func (client *Client) Refresh() error {
-	res, err := client.Get("/api/aaaRefresh", NoRefresh, NoLogPayload)
+	return client.RefreshCtx(context.Background())
+}
+
+// RefreshCtx refreshes the authentication token using the given context.
+func (client *Client) RefreshCtx(ctx context.Context) (err error) {
+	ctx, span := client.tracer().Start(ctx, "nxos.Refresh")
+	defer func() { endSpan(span, err) }()
+	if client.Metrics != nil {
+		start := client.clock().Now()
+		defer func() {
+			client.Metrics.ObserveAuth(client.Url, "refresh", err, client.clock().Now().Sub(start))
+		}()
+	}
+	res, err := client.GetCtx(ctx, "/api/aaaRefresh", NoRefresh, NoLogPayload)
 	if err != nil {
 		return err
 	}
@@ -390,12 +683,18 @@ func (client *Client) Refresh() error {
 
 // Login if no token available or refresh the token if older than 480 seconds.
 func (client *Client) Authenticate() error {
+	return client.AuthenticateCtx(context.Background())
+}
+
+// AuthenticateCtx logs in or refreshes the token using the given context.
+// See Authenticate for details.
+func (client *Client) AuthenticateCtx(ctx context.Context) error {
 	client.authMutex.Lock()
 	defer client.authMutex.Unlock()
 	if client.Token == "" {
-		return client.Login()
+		return client.LoginCtx(ctx)
 	} else if time.Since(client.LastRefresh) > 480*time.Second {
-		return client.Refresh()
+		return client.RefreshCtx(ctx)
 	} else {
 		return nil
 	}
@@ -403,24 +702,27 @@ func (client *Client) Authenticate() error {
 
 // Backoff waits following an exponential backoff algorithm
 func (client *Client) Backoff(attempts int) bool {
-	log.Printf("[DEBUG] Begining backoff method: attempts %v on %v", attempts, client.MaxRetries)
+	return client.BackoffCtx(context.Background(), attempts)
+}
+
+// BackoffCtx waits following an exponential backoff algorithm.
+// It returns false without waiting the full delay if the context is done.
+func (client *Client) BackoffCtx(ctx context.Context, attempts int) bool {
+	logger := client.logger()
+	logger.LogAttrs(ctx, slog.LevelDebug, "Begining backoff method",
+		slog.Int("attempt", attempts),
+		slog.Int("max_retries", client.MaxRetries))
 	if attempts >= client.MaxRetries {
-		log.Printf("[DEBUG] Exit from backoff method with return value false")
+		logger.LogAttrs(ctx, slog.LevelDebug, "Exit from backoff method with return value false")
 		return false
 	}
 
-	minDelay := time.Duration(client.BackoffMinDelay) * time.Second
-	maxDelay := time.Duration(client.BackoffMaxDelay) * time.Second
-
-	min := float64(minDelay)
-	backoff := min * math.Pow(client.BackoffDelayFactor, float64(attempts))
-	if backoff > float64(maxDelay) {
-		backoff = float64(maxDelay)
+	backoffDuration := client.defaultRetryPolicy().Delay(attempts)
+	logger.LogAttrs(ctx, LevelTrace, "Starting sleeping", slog.Duration("delay", backoffDuration.Round(time.Second)))
+	if err := client.clock().Sleep(ctx, backoffDuration); err != nil {
+		logger.LogAttrs(ctx, slog.LevelDebug, "Exit from backoff method with return value false, context done", slog.Any("error", err))
+		return false
 	}
-	backoff = (rand.Float64()/2+0.5)*(backoff-min) + min
-	backoffDuration := time.Duration(backoff)
-	log.Printf("[TRACE] Starting sleeping for %v", backoffDuration.Round(time.Second))
-	time.Sleep(backoffDuration)
-	log.Printf("[DEBUG] Exit from backoff method with return value true")
+	logger.LogAttrs(ctx, slog.LevelDebug, "Exit from backoff method with return value true")
 	return true
 }
@@ -1,6 +1,7 @@
 package nxos
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -303,3 +304,62 @@ func TestClientJsonRpc(t *testing.T) {
 	_, err = client.JsonRpc([]string{"conf t", "interface loopback1", "no shut"})
 	assert.NoError(t, err)
 }
+
+// TestClientGetCtx tests the Client::GetCtx method.
+func TestClientGetCtx(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// Success
+	gock.New(testURL).Get("/url.json").Reply(200)
+	_, err := client.GetCtx(context.Background(), "/url")
+	assert.NoError(t, err)
+
+	// Cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gock.New(testURL).Get("/url.json").Reply(200)
+	_, err = client.GetCtx(ctx, "/url")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestClientBackoffCtx tests the Client::BackoffCtx method.
+func TestClientBackoffCtx(t *testing.T) {
+	client := testClient()
+	client.MaxRetries = 1
+	client.BackoffMinDelay = 60
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.False(t, client.BackoffCtx(ctx, 0))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// TestClientDoCtxRetryCancel tests that cancellation aborts pending retries.
+func TestClientDoCtxRetryCancel(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 3
+	client.BackoffMinDelay = 60
+
+	gock.New(testURL).Get("/url.json").Reply(503)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.DoCtx(ctx, client.NewReq("GET", "/url", nil))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// TestClientLoginCtx tests the Client::LoginCtx method.
+func TestClientLoginCtx(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gock.New(testURL).Post("/api/aaaLogin.json").Reply(200)
+	err := client.LoginCtx(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
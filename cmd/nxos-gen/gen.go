@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// goTypes maps DME property types to Go types.
+var goTypes = map[string]string{
+	"string": "string",
+	"dn":     "string",
+	"bool":   "bool",
+	"int":    "int64",
+	"int8":   "int8",
+	"int16":  "int16",
+	"int32":  "int32",
+	"int64":  "int64",
+	"uint8":  "uint8",
+	"uint16": "uint16",
+	"uint32": "uint32",
+	"uint64": "uint64",
+	"float":  "float64",
+	"time":   "time.Time",
+}
+
+type classView struct {
+	Class     string
+	GoName    string
+	Label     string
+	RnFormat  string
+	RnExpr    string
+	Params    []fieldView
+	Fields    []fieldView
+	Enums     []enumView
+	Children  []childView
+	Parents   []string
+	ParamList string
+	ArgList   string
+	FieldArgs string
+}
+
+type fieldView struct {
+	Prop     string
+	GoName   string
+	Param    string
+	Type     string
+	Tag      string
+	Pointer  bool
+	Naming   bool
+	Enum     bool
+	Min, Max string
+}
+
+type enumView struct {
+	GoName string
+	Prop   string
+	Values []enumValue
+}
+
+type enumValue struct {
+	GoName string
+	Value  string
+}
+
+type childView struct {
+	GoName string
+}
+
+// Generate renders Go source code for all classes of the model.
+func Generate(m *Model, pkg string) ([]byte, error) {
+	var views []classView
+	usesTime := false
+	for _, name := range m.ClassNames() {
+		v := newClassView(m, name)
+		for _, f := range v.Fields {
+			if f.Type == "time.Time" {
+				usesTime = true
+			}
+		}
+		views = append(views, v)
+	}
+	var buf bytes.Buffer
+	err := fileTemplate.Execute(&buf, map[string]any{
+		"Package":  pkg,
+		"UsesTime": usesTime,
+		"Classes":  views,
+	})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("cannot format generated code: %w\n%s", err, buf.String())
+	}
+	return src, nil
+}
+
+// reservedProperties are properties of all objects handled by the nxos package,
+// which are not generated as fields. A field Rn would also conflict with the Rn method.
+var reservedProperties = map[string]bool{
+	"rn":          true,
+	"dn":          true,
+	"status":      true,
+	"childAction": true,
+}
+
+func newClassView(m *Model, name string) classView {
+	c := m.Classes[name]
+	v := classView{
+		Class:    name,
+		GoName:   exportedName(name),
+		Label:    c.Label,
+		RnFormat: c.RnFormat,
+	}
+	for _, pname := range c.PropertyNames() {
+		p := c.Properties[pname]
+		if reservedProperties[pname] && !p.IsNaming {
+			continue
+		}
+		f := fieldView{
+			Prop:   pname,
+			GoName: exportedName(pname),
+			Param:  paramName(pname),
+			Naming: p.IsNaming,
+		}
+		goType, ok := goTypes[p.Type]
+		if !ok {
+			goType = "string"
+		}
+		if p.Type == "enum" {
+			f.Enum = true
+			goType = v.GoName + f.GoName
+			e := enumView{GoName: goType, Prop: pname}
+			used := map[string]bool{}
+			for _, val := range p.Values {
+				// Values differing only in case or punctuation get a numeric suffix
+				name := goType + exportedName(val)
+				for i := 2; used[name]; i++ {
+					name = goType + exportedName(val) + strconv.Itoa(i)
+				}
+				used[name] = true
+				e.Values = append(e.Values, enumValue{GoName: name, Value: val})
+			}
+			v.Enums = append(v.Enums, e)
+		}
+		f.Type = goType
+		if p.Min != nil {
+			f.Min = strconv.FormatInt(*p.Min, 10)
+		}
+		if p.Max != nil {
+			f.Max = strconv.FormatInt(*p.Max, 10)
+		}
+		// Non-naming scalars are pointers to distinguish unset values from zero values.
+		f.Pointer = !p.IsNaming && goType != "string" && !f.Enum
+		f.Tag = pname
+		if !p.IsNaming {
+			f.Tag += ",omitempty"
+		}
+		if !p.IsNaming && !p.IsConfig {
+			f.Tag += ",readonly"
+		}
+		if p.IsNaming {
+			v.Params = append(v.Params, f)
+		}
+		v.Fields = append(v.Fields, f)
+	}
+	for _, child := range c.Contains {
+		if _, ok := m.Classes[child]; ok {
+			v.Children = append(v.Children, childView{GoName: exportedName(child)})
+		}
+	}
+	v.Parents = c.ContainedBy
+
+	var params, args, fieldArgs []string
+	for _, p := range v.Params {
+		params = append(params, p.Param+" "+p.Type)
+		args = append(args, p.Param)
+		fieldArgs = append(fieldArgs, "o."+p.GoName)
+	}
+	v.ParamList = strings.Join(params, ", ")
+	v.ArgList = strings.Join(args, ", ")
+	v.FieldArgs = strings.Join(fieldArgs, ", ")
+	v.RnExpr = rnExpr(c.RnFormat, v.Params)
+	return v
+}
+
+// rnExpr returns a Go expression building the RN from the naming parameters.
+func rnExpr(rnFormat string, params []fieldView) string {
+	byProp := map[string]fieldView{}
+	for _, p := range params {
+		byProp[p.Prop] = p
+	}
+	var parts []string
+	rest := rnFormat
+	for _, loc := range rnPlaceholder.FindAllStringSubmatchIndex(rnFormat, -1) {
+		offset := len(rnFormat) - len(rest)
+		if lit := rest[:loc[0]-offset]; lit != "" {
+			parts = append(parts, strconv.Quote(lit))
+		}
+		p := byProp[rnFormat[loc[2]:loc[3]]]
+		if p.Type == "string" {
+			parts = append(parts, p.Param)
+		} else {
+			parts = append(parts, "fmt.Sprint("+p.Param+")")
+		}
+		rest = rnFormat[loc[1]:]
+	}
+	if rest != "" {
+		parts = append(parts, strconv.Quote(rest))
+	}
+	return "nxos.Rn(" + strings.Join(parts, " + ") + ")"
+}
+
+// exportedName converts a DME name to an exported Go identifier, e.g. l1PhysIf -> L1PhysIf.
+func exportedName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "V" + name
+	}
+	return name
+}
+
+// paramName converts a DME property name to an unexported Go identifier.
+func paramName(s string) string {
+	name := exportedName(s)
+	name = strings.ToLower(name[:1]) + name[1:]
+	if token.IsKeyword(name) {
+		name += "_"
+	}
+	return name
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by nxos-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"fmt"
+{{- if .UsesTime}}
+	"time"
+{{- end}}
+
+	"github.com/netascode/go-nxos"
+)
+{{range .Classes}}{{$c := .}}
+// {{.GoName}}Class is the class name of {{.GoName}}.
+const {{.GoName}}Class = "{{.Class}}"
+
+// {{.GoName}} is the {{.Class}} managed object{{if .Label}} ({{.Label}}){{end}}.
+{{- if .Parents}}
+// It is contained by {{range $i, $p := .Parents}}{{if $i}}, {{end}}{{$p}}{{end}}.
+{{- end}}
+type {{.GoName}} struct {
+	_ struct{} ` + "`" + `nxos:"class={{.Class}}"` + "`" + `
+{{- range .Fields}}
+	{{.GoName}} {{if .Pointer}}*{{end}}{{.Type}} ` + "`" + `nxos:"{{.Tag}}"` + "`" + `
+{{- end}}
+{{- range .Children}}
+	{{.GoName}} []{{.GoName}} ` + "`" + `nxos:",children"` + "`" + `
+{{- end}}
+}
+{{range .Enums}}{{$e := .}}
+// {{.GoName}} is the {{.Prop}} property of {{$c.Class}}.
+type {{.GoName}} string
+
+// Values of {{.GoName}}.
+const (
+{{- range .Values}}
+	{{.GoName}} {{$e.GoName}} = {{printf "%q" .Value}}
+{{- end}}
+)
+
+// Validate checks that the value is a known {{.Prop}} value.
+func (v {{.GoName}}) Validate() error {
+	switch v {
+	case {{range $i, $v := .Values}}{{if $i}}, {{end}}{{$v.GoName}}{{end}}:
+		return nil
+	}
+	return fmt.Errorf("invalid {{$c.Class}}.{{.Prop}} %q: %w", string(v), nxos.ErrInvalidArgument)
+}
+{{end}}
+// {{.GoName}}Rn returns the RN of the {{.Class}} object ({{.RnFormat}}).
+func {{.GoName}}Rn({{.ParamList}}) nxos.Rn {
+	return {{.RnExpr}}
+}
+
+// Rn returns the RN of the object.
+func (o *{{.GoName}}) Rn() nxos.Rn {
+	return {{.GoName}}Rn({{.FieldArgs}})
+}
+
+// Validate checks naming properties, enum values and ranges of the object and its children.
+func (o *{{.GoName}}) Validate() error {
+{{- range .Fields}}
+{{- if and .Naming (eq .Type "string")}}
+	if o.{{.GoName}} == "" {
+		return fmt.Errorf("{{$c.Class}}.{{.Prop}} is required: %w", nxos.ErrInvalidArgument)
+	}
+{{- end}}
+{{- if .Enum}}
+	if o.{{.GoName}} != "" {
+		if err := o.{{.GoName}}.Validate(); err != nil {
+			return err
+		}
+	}
+{{- end}}
+{{- if or .Min .Max}}
+	if {{if .Pointer}}o.{{.GoName}} != nil && {{end}}({{if .Min}}{{if .Pointer}}*{{end}}o.{{.GoName}} < {{.Min}}{{end}}{{if and .Min .Max}} || {{end}}{{if .Max}}{{if .Pointer}}*{{end}}o.{{.GoName}} > {{.Max}}{{end}}) {
+		return fmt.Errorf("{{$c.Class}}.{{.Prop}} %v out of range [{{.Min}}, {{.Max}}]: %w", {{if .Pointer}}*{{end}}o.{{.GoName}}, nxos.ErrInvalidArgument)
+	}
+{{- end}}
+{{- end}}
+{{- range .Children}}
+	for i := range o.{{.GoName}} {
+		if err := o.{{.GoName}}[i].Validate(); err != nil {
+			return err
+		}
+	}
+{{- end}}
+	return nil
+}
+
+// Get{{.GoName}} retrieves the {{.Class}} object below parent.
+// It returns an error wrapping nxos.ErrNotFound if the object does not exist.
+func Get{{.GoName}}(ctx context.Context, client *nxos.Client, parent nxos.Dn{{if .ParamList}}, {{.ParamList}}{{end}}, mods ...func(*nxos.Req)) (*{{.GoName}}, error) {
+	dn := parent.Child({{.GoName}}Rn({{.ArgList}}))
+	res, err := client.GetMoCtx(ctx, dn, mods...)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Exists() {
+		return nil, fmt.Errorf("%s: %w", dn, nxos.ErrNotFound)
+	}
+	o := &{{.GoName}}{}
+	if err := nxos.Unmarshal(res, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Post validates the object and creates or updates it below parent.
+func (o *{{.GoName}}) Post(ctx context.Context, client *nxos.Client, parent nxos.Dn, mods ...func(*nxos.Req)) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	body, err := nxos.Marshal(o)
+	if err != nil {
+		return err
+	}
+	_, err = client.PostMoCtx(ctx, parent.Child(o.Rn()), body.Str, mods...)
+	return err
+}
+
+// Delete{{.GoName}} deletes the {{.Class}} object below parent.
+func Delete{{.GoName}}(ctx context.Context, client *nxos.Client, parent nxos.Dn{{if .ParamList}}, {{.ParamList}}{{end}}, mods ...func(*nxos.Req)) error {
+	_, err := client.DeleteMoCtx(ctx, parent.Child({{.GoName}}Rn({{.ArgList}})), mods...)
+	return err
+}
+{{end}}`))
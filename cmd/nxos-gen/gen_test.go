@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// TestGenerate tests the generated code against the golden file.
+func TestGenerate(t *testing.T) {
+	m, err := LoadModel("testdata/model.json")
+	assert.NoError(t, err)
+	src, err := Generate(m, "model")
+	assert.NoError(t, err)
+	if *update {
+		assert.NoError(t, os.WriteFile("testdata/model.go.golden", src, 0o644))
+	}
+	golden, err := os.ReadFile("testdata/model.go.golden")
+	assert.NoError(t, err)
+	assert.Equal(t, string(golden), string(src))
+}
+
+// TestGenerateFilter tests generating a subset of classes.
+func TestGenerateFilter(t *testing.T) {
+	m, err := LoadModel("testdata/model.json")
+	assert.NoError(t, err)
+	assert.NoError(t, m.Filter([]string{"l1PhysIf"}))
+	src, err := Generate(m, "model")
+	assert.NoError(t, err)
+	assert.Contains(t, string(src), "func L1PhysIfRn(id string) nxos.Rn")
+	// Reserved properties are not generated as fields
+	assert.NotContains(t, string(src), `nxos:"rn`)
+	assert.NotContains(t, string(src), `nxos:"status`)
+	assert.NotContains(t, string(src), "BgpPeer")
+	assert.Error(t, m.Filter([]string{"unknown"}))
+}
+
+// TestModelValidate tests the validation of model metadata.
+func TestModelValidate(t *testing.T) {
+	m := &Model{Classes: map[string]*Class{
+		"l1PhysIf": {RnFormat: "phys-[{id}]", Properties: map[string]*Property{"id": {Type: "string"}}},
+	}}
+	assert.NoError(t, m.Validate())
+	assert.True(t, m.Classes["l1PhysIf"].Properties["id"].IsNaming)
+
+	m.Classes["l1PhysIf"].RnFormat = "phys-[{name}]"
+	assert.ErrorContains(t, m.Validate(), "unknown property name")
+
+	m.Classes["l1PhysIf"].RnFormat = ""
+	assert.ErrorContains(t, m.Validate(), "missing rnFormat")
+
+	m.Classes["l1PhysIf"].RnFormat = "phys-[{id}]"
+	m.Classes["l1PhysIf"].Properties["adminSt"] = &Property{Type: "enum"}
+	assert.ErrorContains(t, m.Validate(), "no values")
+
+	assert.Error(t, (&Model{}).Validate())
+}
+
+// TestNames tests the conversion of DME names to Go identifiers.
+func TestNames(t *testing.T) {
+	assert.Equal(t, "L1PhysIf", exportedName("l1PhysIf"))
+	assert.Equal(t, "V10G", exportedName("10G"))
+	assert.Equal(t, "AutoNeg", exportedName("auto-neg"))
+	assert.Equal(t, "type_", paramName("type"))
+	assert.Equal(t, "fabEncap", paramName("fabEncap"))
+}
+
+// TestRun tests the command line interface.
+func TestRun(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "model_gen.go")
+	assert.NoError(t, run([]string{"-model", "testdata/model.json", "-package", "model", "-classes", "l2BD", "-out", out}))
+	src, err := os.ReadFile(out)
+	assert.NoError(t, err)
+	assert.Contains(t, string(src), `nxos.Rn("bd-[vlan-" + fmt.Sprint(id) + "]")`)
+
+	assert.Error(t, run([]string{}))
+	assert.Error(t, run([]string{"-model", "testdata/missing.json"}))
+}
+
+// TestGenerateTypeCheck type-checks the golden file against the nxos package, so that
+// API changes of the client break the test rather than the generated code.
+func TestGenerateTypeCheck(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "testdata/model.go.golden", nil, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("model", fset, []*ast.File{f}, nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,56 @@
+// Command nxos-gen generates typed Go code for DME classes from NX-OS model metadata.
+//
+// For every class it generates a struct usable with nxos.Marshal and nxos.Unmarshal,
+// enum types, an RN builder, a Validate method and CRUD helpers wrapping
+// Client.GetDn, Client.Post and Client.DeleteDn, e.g.
+//
+//	nxos-gen -model model.json -package model -out model/model_gen.go -classes l1PhysIf,interfaceEntity
+//
+// See Model for the metadata format.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "nxos-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("nxos-gen", flag.ContinueOnError)
+	modelPath := flags.String("model", "", "path to the model metadata JSON file")
+	out := flags.String("out", "", "output file (default stdout)")
+	pkg := flags.String("package", "model", "package name of the generated code")
+	classes := flags.String("classes", "", "comma-separated list of classes to generate (default all)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *modelPath == "" {
+		return fmt.Errorf("missing -model")
+	}
+	m, err := LoadModel(*modelPath)
+	if err != nil {
+		return err
+	}
+	if *classes != "" {
+		if err := m.Filter(strings.Split(*classes, ",")); err != nil {
+			return err
+		}
+	}
+	src, err := Generate(m, *pkg)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0o644)
+}
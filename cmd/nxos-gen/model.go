@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Model is the DME model metadata, e.g.
+//
+//	{
+//	  "classes": {
+//	    "l1PhysIf": {
+//	      "label": "Physical Interface",
+//	      "rnFormat": "phys-[{id}]",
+//	      "containedBy": ["interfaceEntity"],
+//	      "contains": ["ethpmPhysIf"],
+//	      "properties": {
+//	        "id": {"type": "string", "isNaming": true},
+//	        "mtu": {"type": "uint32", "isConfig": true, "min": 576, "max": 9216},
+//	        "adminSt": {"type": "enum", "isConfig": true, "values": ["up", "down"]}
+//	      }
+//	    }
+//	  }
+//	}
+type Model struct {
+	Classes map[string]*Class `json:"classes"`
+}
+
+// Class is the metadata of a DME class.
+type Class struct {
+	Label       string               `json:"label"`
+	RnFormat    string               `json:"rnFormat"`
+	ContainedBy []string             `json:"containedBy"`
+	Contains    []string             `json:"contains"`
+	Properties  map[string]*Property `json:"properties"`
+}
+
+// Property is the metadata of a DME class property.
+type Property struct {
+	// Type is one of string, bool, int, int8, int16, int32, int64, uint8, uint16,
+	// uint32, uint64, float, enum, time or dn. Unknown types are treated as string.
+	Type     string   `json:"type"`
+	Label    string   `json:"label"`
+	IsNaming bool     `json:"isNaming"`
+	IsConfig bool     `json:"isConfig"`
+	Values   []string `json:"values"`
+	Min      *int64   `json:"min"`
+	Max      *int64   `json:"max"`
+}
+
+// LoadModel reads and validates model metadata from a JSON file.
+func LoadModel(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid model %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+var rnPlaceholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// Validate checks that RN formats only reference naming properties and that enums have values.
+func (m *Model) Validate() error {
+	if len(m.Classes) == 0 {
+		return fmt.Errorf("no classes defined")
+	}
+	for _, name := range m.ClassNames() {
+		c := m.Classes[name]
+		if c.RnFormat == "" {
+			return fmt.Errorf("class %s: missing rnFormat", name)
+		}
+		for _, prop := range c.NamingProps() {
+			p, ok := c.Properties[prop]
+			if !ok {
+				return fmt.Errorf("class %s: rnFormat references unknown property %s", name, prop)
+			}
+			p.IsNaming = true
+		}
+		for pname, p := range c.Properties {
+			if p.Type == "enum" && len(p.Values) == 0 {
+				return fmt.Errorf("class %s: enum property %s has no values", name, pname)
+			}
+		}
+	}
+	return nil
+}
+
+// ClassNames returns the class names in sorted order.
+func (m *Model) ClassNames() []string {
+	names := make([]string, 0, len(m.Classes))
+	for name := range m.Classes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Filter restricts the model to the given classes.
+func (m *Model) Filter(classes []string) error {
+	filtered := map[string]*Class{}
+	for _, name := range classes {
+		c, ok := m.Classes[name]
+		if !ok {
+			return fmt.Errorf("unknown class %s", name)
+		}
+		filtered[name] = c
+	}
+	m.Classes = filtered
+	return nil
+}
+
+// NamingProps returns the naming properties in the order they appear in the RN format.
+func (c *Class) NamingProps() []string {
+	var props []string
+	for _, match := range rnPlaceholder.FindAllStringSubmatch(c.RnFormat, -1) {
+		props = append(props, match[1])
+	}
+	return props
+}
+
+// PropertyNames returns the property names with naming properties first, then in sorted order.
+func (c *Class) PropertyNames() []string {
+	naming := c.NamingProps()
+	names := append([]string{}, naming...)
+	var rest []string
+	for name := range c.Properties {
+		if !strings.Contains(c.RnFormat, "{"+name+"}") {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
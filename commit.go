@@ -0,0 +1,400 @@
+package nxos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CommitState is the state of a commit-confirm change.
+type CommitState int
+
+const (
+	// CommitPending waits for confirmation.
+	CommitPending CommitState = iota
+	// CommitConfirmed was confirmed in time.
+	CommitConfirmed
+	// CommitRolledBack was rolled back, either explicitly, because the deadline expired
+	// or because the device became unreachable.
+	CommitRolledBack
+	// CommitRollbackFailed could not be rolled back by the client. If an on-box rollback
+	// was scheduled, the device rolls back by itself.
+	CommitRollbackFailed
+)
+
+func (s CommitState) String() string {
+	switch s {
+	case CommitPending:
+		return "pending"
+	case CommitConfirmed:
+		return "confirmed"
+	case CommitRolledBack:
+		return "rolled back"
+	case CommitRollbackFailed:
+		return "rollback failed"
+	}
+	return "unknown"
+}
+
+// ErrCommitNotPending is returned when confirming or rolling back a commit which is no longer pending.
+var ErrCommitNotPending = errors.New("commit is not pending")
+
+// ErrCommitCleanup is returned by Confirm if the change was confirmed, but the checkpoint
+// could not be deleted.
+var ErrCommitCleanup = errors.New("commit confirmed, but cleanup failed")
+
+// CommitConfirmOptions are the options of CommitConfirm.
+type CommitConfirmOptions struct {
+	// Name is the name of the checkpoint and the scheduler job, defaults to nxos-cc-<unix time in nanoseconds>.
+	Name string
+	// Mode is the rollback mode, defaults to RollbackAtomic.
+	Mode RollbackMode
+	// OnBox schedules a rollback with the NX-OS scheduler, so that the device rolls back
+	// even if the client is gone. Enabled by default.
+	OnBox bool
+	// OnBoxGrace is the delay of the on-box rollback after the deadline, giving the
+	// client the chance to roll back first. Defaults to one minute.
+	OnBoxGrace time.Duration
+	// ProbeInterval is the interval of reachability checks while waiting for confirmation.
+	// Zero disables reachability checks.
+	ProbeInterval time.Duration
+	// ProbeFailures is the number of consecutive failed reachability checks triggering
+	// a rollback. Defaults to 3.
+	ProbeFailures int
+	// Mods are request modifiers applied to all requests made by CommitConfirm.
+	Mods []func(*Req)
+}
+
+// CommitName sets the name of the checkpoint and scheduler job of CommitConfirm.
+func CommitName(name string) func(*CommitConfirmOptions) {
+	return func(o *CommitConfirmOptions) {
+		o.Name = name
+	}
+}
+
+// CommitRollbackMode sets the rollback mode of CommitConfirm.
+func CommitRollbackMode(mode RollbackMode) func(*CommitConfirmOptions) {
+	return func(o *CommitConfirmOptions) {
+		o.Mode = mode
+	}
+}
+
+// NoOnBoxRollback disables the on-box rollback scheduled with the NX-OS scheduler.
+func NoOnBoxRollback() func(*CommitConfirmOptions) {
+	return func(o *CommitConfirmOptions) {
+		o.OnBox = false
+	}
+}
+
+// OnBoxGrace sets the delay of the on-box rollback after the deadline.
+func OnBoxGrace(d time.Duration) func(*CommitConfirmOptions) {
+	return func(o *CommitConfirmOptions) {
+		o.OnBoxGrace = d
+	}
+}
+
+// ProbeReachability checks the reachability of the device at the given interval and rolls
+// back after the given number of consecutive failures.
+func ProbeReachability(interval time.Duration, failures int) func(*CommitConfirmOptions) {
+	return func(o *CommitConfirmOptions) {
+		o.ProbeInterval = interval
+		o.ProbeFailures = failures
+	}
+}
+
+// CommitReqMods sets request modifiers used for all requests made by CommitConfirm.
+func CommitReqMods(mods ...func(*Req)) func(*CommitConfirmOptions) {
+	return func(o *CommitConfirmOptions) {
+		o.Mods = append(o.Mods, mods...)
+	}
+}
+
+// PendingCommit is a change applied by CommitConfirm waiting for confirmation.
+type PendingCommit struct {
+	client   *Client
+	opts     CommitConfirmOptions
+	deadline time.Time
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	// op serializes confirmation and rollback, which make requests to the device.
+	// mu only protects the state and is not held during requests.
+	op    sync.Mutex
+	mu    sync.Mutex
+	state CommitState
+	err   error
+}
+
+// commitRollbackTimeout bounds the requests of a rollback, which is not canceled
+// together with the context of the caller.
+var commitRollbackTimeout = 5 * time.Minute
+
+// CommitConfirm applies a change which must be confirmed before the timeout expires,
+// similar to "commit confirmed" on other platforms, e.g.
+//
+//	commit, err := client.CommitConfirm(5*time.Minute, func() error {
+//	    _, err := client.Post("sys/intf/phys-[mgmt0]", body)
+//	    return err
+//	}, nxos.ProbeReachability(10*time.Second, 3))
+//	if err != nil {
+//	    return err
+//	}
+//	// verify the change, then
+//	err = commit.Confirm()
+//
+// A checkpoint is created before fn applies the change. If fn fails, the change is rolled
+// back immediately. Otherwise the change is rolled back if it is not confirmed before the
+// deadline or if the device is unreachable for the configured number of reachability checks.
+// Unless NoOnBoxRollback is passed, a rollback is also scheduled with the NX-OS scheduler
+// (feature scheduler) shortly after the deadline, so that the device rolls back by itself
+// if the client can no longer reach it. The scheduler is configured before the checkpoint
+// is created and the job removes itself after the rollback. The scheduler has a resolution
+// of one minute.
+func (client *Client) CommitConfirm(timeout time.Duration, fn func() error, opts ...func(*CommitConfirmOptions)) (*PendingCommit, error) {
+	return client.CommitConfirmCtx(context.Background(), timeout, fn, opts...)
+}
+
+// CommitConfirmCtx applies a change which must be confirmed using the given context for the
+// initial requests. See CommitConfirm for details.
+func (client *Client) CommitConfirmCtx(ctx context.Context, timeout time.Duration, fn func() error, opts ...func(*CommitConfirmOptions)) (*PendingCommit, error) {
+	o := CommitConfirmOptions{Mode: RollbackAtomic, OnBox: true, OnBoxGrace: time.Minute, ProbeFailures: 3}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	clock := client.clock()
+	if o.Name == "" {
+		o.Name = fmt.Sprintf("nxos-cc-%d", clock.Now().UnixNano())
+	}
+	if err := checkCheckpointName(o.Name); err != nil {
+		return nil, err
+	}
+	p := &PendingCommit{
+		client:   client,
+		opts:     o,
+		deadline: clock.Now().Add(timeout),
+		done:     make(chan struct{}),
+	}
+	// The scheduler is configured before the checkpoint is created, so that a rollback
+	// to the checkpoint keeps the scheduler job.
+	if o.OnBox {
+		if err := p.schedule(ctx, timeout+o.OnBoxGrace); err != nil {
+			p.unschedule(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("cannot schedule on-box rollback: %w", err)
+		}
+	}
+	if err := client.CreateCheckpointCtx(ctx, o.Name, "commit confirm", o.Mods...); err != nil {
+		if o.OnBox {
+			p.unschedule(context.WithoutCancel(ctx))
+		}
+		return nil, fmt.Errorf("cannot create checkpoint %s: %w", o.Name, err)
+	}
+	if err := fn(); err != nil {
+		rbErr := p.rollback(ctx, errors.New("rolled back"))
+		return nil, errors.Join(fmt.Errorf("change failed: %w", err), rbErr)
+	}
+	watchCtx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.watch(watchCtx)
+	return p, nil
+}
+
+// Deadline returns the time by which the change must be confirmed.
+func (p *PendingCommit) Deadline() time.Time {
+	return p.deadline
+}
+
+// Name returns the name of the checkpoint and scheduler job.
+func (p *PendingCommit) Name() string {
+	return p.opts.Name
+}
+
+// State returns the current state.
+func (p *PendingCommit) State() CommitState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// Done returns a channel which is closed when the commit is confirmed or rolled back.
+func (p *PendingCommit) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err returns the reason of the rollback, or nil if the commit is pending or confirmed.
+func (p *PendingCommit) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Confirm confirms the change. The device must be reachable; the on-box rollback and the
+// checkpoint are removed. If confirmation fails, the commit stays pending. Confirm fails
+// with ErrCommitNotPending after the deadline, even if the rollback has not started yet.
+// The commit is confirmed once the on-box rollback is removed; if the checkpoint cannot be
+// deleted afterwards, the returned error wraps ErrCommitCleanup and the commit stays confirmed.
+func (p *PendingCommit) Confirm() error {
+	return p.ConfirmCtx(context.Background())
+}
+
+// ConfirmCtx confirms the change using the given context. See Confirm for details.
+func (p *PendingCommit) ConfirmCtx(ctx context.Context) error {
+	p.op.Lock()
+	defer p.op.Unlock()
+	if state := p.State(); state != CommitPending {
+		return fmt.Errorf("%w: %s", ErrCommitNotPending, state)
+	}
+	if !p.client.clock().Now().Before(p.deadline) {
+		return fmt.Errorf("%w: confirmation deadline expired", ErrCommitNotPending)
+	}
+	if err := p.probe(ctx); err != nil {
+		return fmt.Errorf("device unreachable: %w", err)
+	}
+	if p.opts.OnBox {
+		if err := p.unschedule(ctx); err != nil {
+			return fmt.Errorf("cannot remove on-box rollback: %w", err)
+		}
+	}
+	p.finish(CommitConfirmed, nil)
+	if err := p.client.DeleteCheckpointCtx(ctx, p.opts.Name, p.opts.Mods...); err != nil {
+		return fmt.Errorf("%w: cannot delete checkpoint %s: %w", ErrCommitCleanup, p.opts.Name, err)
+	}
+	return nil
+}
+
+// Rollback rolls back the change immediately.
+func (p *PendingCommit) Rollback() error {
+	p.op.Lock()
+	defer p.op.Unlock()
+	if state := p.State(); state != CommitPending {
+		return fmt.Errorf("%w: %s", ErrCommitNotPending, state)
+	}
+	return p.rollback(context.Background(), errors.New("rolled back"))
+}
+
+// rollback rolls back to the checkpoint with p.op held. The requests are not canceled
+// with ctx but are bounded by commitRollbackTimeout.
+func (p *PendingCommit) rollback(ctx context.Context, reason error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), commitRollbackTimeout)
+	defer cancel()
+	if _, err := p.client.RollbackToCtx(ctx, p.opts.Name, p.opts.Mode, p.opts.Mods...); err != nil {
+		err = errors.Join(reason, err)
+		p.finish(CommitRollbackFailed, err)
+		return err
+	}
+	p.finish(CommitRolledBack, reason)
+	p.cleanup(ctx, p.opts.OnBox)
+	return nil
+}
+
+// finish sets the final state.
+func (p *PendingCommit) finish(state CommitState, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state, p.err = state, err
+	if p.cancel != nil {
+		p.cancel()
+	}
+	close(p.done)
+}
+
+// cleanup removes the scheduler job and the checkpoint, ignoring errors.
+func (p *PendingCommit) cleanup(ctx context.Context, unschedule bool) {
+	if unschedule {
+		p.unschedule(ctx)
+	}
+	p.client.DeleteCheckpointCtx(ctx, p.opts.Name, p.opts.Mods...)
+}
+
+// watch rolls back when the deadline expires or the device becomes unreachable.
+func (p *PendingCommit) watch(ctx context.Context) {
+	clock := p.client.clock()
+	failures := 0
+	for {
+		wait := p.deadline.Sub(clock.Now())
+		if p.opts.ProbeInterval > 0 && p.opts.ProbeInterval < wait {
+			wait = p.opts.ProbeInterval
+		}
+		if wait > 0 {
+			if err := clock.Sleep(ctx, wait); err != nil {
+				return
+			}
+		}
+		var reason error
+		if !clock.Now().Before(p.deadline) {
+			reason = errors.New("confirmation deadline expired")
+		} else if p.opts.ProbeInterval > 0 {
+			if err := p.probe(ctx); err == nil {
+				failures = 0
+			} else if failures++; failures >= p.opts.ProbeFailures {
+				reason = fmt.Errorf("device unreachable: %w", err)
+			}
+		}
+		if reason == nil {
+			continue
+		}
+		p.op.Lock()
+		if p.State() == CommitPending {
+			p.rollback(ctx, reason)
+		}
+		p.op.Unlock()
+		return
+	}
+}
+
+// probe checks that the device is reachable.
+func (p *PendingCommit) probe(ctx context.Context) error {
+	mods := append([]func(*Req){Query("query-target", "self"), NoRetry}, p.opts.Mods...)
+	_, err := p.client.GetDnCtx(ctx, "sys", mods...)
+	return err
+}
+
+// schedule schedules the on-box rollback with the NX-OS scheduler. After the rollback,
+// the job removes its schedule and itself.
+func (p *PendingCommit) schedule(ctx context.Context, delay time.Duration) error {
+	cmds := []string{
+		"configure terminal",
+		"feature scheduler",
+		"scheduler job name " + p.opts.Name,
+		fmt.Sprintf("rollback running-config checkpoint %s %s", p.opts.Name, p.opts.Mode),
+		"configure terminal",
+		"no scheduler schedule name " + p.opts.Name,
+		"no scheduler job name " + p.opts.Name,
+		"exit",
+		"scheduler schedule name " + p.opts.Name,
+		"job name " + p.opts.Name,
+		"time start " + schedulerDelta(delay),
+		"exit",
+	}
+	_, err := p.client.JsonRpcCtx(ctx, cmds, p.opts.Mods...)
+	return err
+}
+
+// unschedule removes the on-box rollback.
+func (p *PendingCommit) unschedule(ctx context.Context) error {
+	cmds := []string{
+		"configure terminal",
+		"no scheduler schedule name " + p.opts.Name,
+		"no scheduler job name " + p.opts.Name,
+	}
+	_, err := p.client.JsonRpcCtx(ctx, cmds, p.opts.Mods...)
+	return err
+}
+
+// schedulerDelta formats a delay as NX-OS scheduler delta time (+[[dd:]hh:]mm),
+// rounded up to full minutes.
+func schedulerDelta(d time.Duration) string {
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	days, hours, mins := minutes/(24*60), minutes/60%24, minutes%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("+%d:%02d:%02d", days, hours, mins)
+	case hours > 0:
+		return fmt.Sprintf("+%d:%02d", hours, mins)
+	}
+	return fmt.Sprintf("+%d", mins)
+}
@@ -0,0 +1,199 @@
+package nxos
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// TestClientCommitConfirm tests confirming a commit.
+func TestClientCommitConfirm(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint cc1 description commit confirm"`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Post("/ins").
+		BodyString(`"cmd":"scheduler job name cc1".*"cmd":"rollback running-config checkpoint cc1 atomic".*"cmd":"no scheduler job name cc1".*"cmd":"time start \+2"`).
+		Reply(200)
+	gock.New(testURL).Get("/api/mo/sys.json").MatchParam("query-target", "self").Reply(200)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no scheduler schedule name cc1"`).Reply(200)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no checkpoint cc1"`).Reply(200).BodyString(cliAsciiReply(""))
+
+	applied := false
+	commit, err := client.CommitConfirm(30*time.Second, func() error {
+		applied = true
+		return nil
+	}, CommitName("cc1"))
+	assert.NoError(t, err)
+	assert.True(t, applied)
+	assert.Equal(t, CommitPending, commit.State())
+	assert.Equal(t, "cc1", commit.Name())
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), commit.Deadline(), time.Second)
+
+	assert.NoError(t, commit.Confirm())
+	assert.Equal(t, CommitConfirmed, commit.State())
+	assert.NoError(t, commit.Err())
+	<-commit.Done()
+	assert.True(t, gock.IsDone())
+
+	assert.ErrorIs(t, commit.Confirm(), ErrCommitNotPending)
+	assert.ErrorIs(t, commit.Rollback(), ErrCommitNotPending)
+
+	// Failing to delete the checkpoint does not fail the confirmation
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint cc8`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Get("/api/mo/sys.json").Reply(200)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no checkpoint cc8"`).Reply(200).
+		BodyString(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`)
+	commit, err = client.CommitConfirm(time.Minute, func() error { return nil }, CommitName("cc8"), NoOnBoxRollback())
+	assert.NoError(t, err)
+	err = commit.Confirm()
+	assert.ErrorIs(t, err, ErrCommitCleanup)
+	assert.ErrorContains(t, err, "cannot delete checkpoint cc8")
+	assert.Equal(t, CommitConfirmed, commit.State())
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientCommitConfirmExpired tests the rollback after the deadline.
+func TestClientCommitConfirmExpired(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint cc2`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"rollback running-config checkpoint cc2 best-effort"`).
+		Reply(200).BodyString(cliAsciiReply("Rollback completed successfully.\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no checkpoint cc2"`).Reply(200).BodyString(cliAsciiReply(""))
+
+	commit, err := client.CommitConfirm(20*time.Millisecond, func() error { return nil },
+		CommitName("cc2"), CommitRollbackMode(RollbackBestEffort), NoOnBoxRollback())
+	assert.NoError(t, err)
+	select {
+	case <-commit.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no rollback")
+	}
+	assert.Equal(t, CommitRolledBack, commit.State())
+	assert.ErrorContains(t, commit.Err(), "deadline expired")
+	assert.True(t, gock.IsDone())
+}
+
+// stoppedClock is a Clock which only advances when set and blocks on Sleep until
+// the context is done.
+type stoppedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *stoppedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *stoppedClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+func (c *stoppedClock) Sleep(ctx context.Context, d time.Duration) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// TestClientCommitConfirmLate tests confirming a commit after the deadline.
+func TestClientCommitConfirmLate(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	clock := &stoppedClock{now: time.Now()}
+	TimeSource(clock)(client)
+
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint cc6`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"rollback running-config checkpoint cc6 atomic"`).
+		Reply(200).BodyString(cliAsciiReply("Rollback completed successfully.\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no checkpoint cc6"`).Reply(200).BodyString(cliAsciiReply(""))
+
+	commit, err := client.CommitConfirm(time.Minute, func() error { return nil },
+		CommitName("cc6"), NoOnBoxRollback())
+	assert.NoError(t, err)
+	clock.Set(commit.Deadline())
+	err = commit.Confirm()
+	assert.ErrorIs(t, err, ErrCommitNotPending)
+	assert.ErrorContains(t, err, "deadline expired")
+	assert.Equal(t, CommitPending, commit.State())
+
+	assert.NoError(t, commit.Rollback())
+	assert.Equal(t, CommitRolledBack, commit.State())
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientCommitConfirmUnreachable tests the rollback when the device becomes unreachable.
+func TestClientCommitConfirmUnreachable(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint cc3`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Get("/api/mo/sys.json").Times(2).ReplyError(errors.New("unreachable"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"rollback`).Reply(200).BodyString(cliAsciiReply("Rollback failed.\n"))
+
+	commit, err := client.CommitConfirm(time.Minute, func() error { return nil },
+		CommitName("cc3"), NoOnBoxRollback(), ProbeReachability(5*time.Millisecond, 2))
+	assert.NoError(t, err)
+	select {
+	case <-commit.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("no rollback")
+	}
+	assert.Equal(t, CommitRollbackFailed, commit.State())
+	assert.ErrorContains(t, commit.Err(), "device unreachable")
+	assert.ErrorContains(t, commit.Err(), "Rollback failed.")
+	assert.True(t, gock.IsDone())
+}
+
+// TestClientCommitConfirmFailed tests the rollback when applying the change fails.
+func TestClientCommitConfirmFailed(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint cc4`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"scheduler job name cc4"`).Reply(200)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"rollback`).Reply(200).BodyString(cliAsciiReply("Rollback completed successfully.\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no scheduler schedule name cc4"`).Reply(200)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no checkpoint cc4"`).Reply(200).BodyString(cliAsciiReply(""))
+
+	fail := errors.New("fail")
+	commit, err := client.CommitConfirm(time.Minute, func() error { return fail }, CommitName("cc4"))
+	assert.Nil(t, commit)
+	assert.ErrorIs(t, err, fail)
+	assert.True(t, gock.IsDone())
+
+	// Scheduling fails, no checkpoint is created
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"scheduler job name cc5"`).Reply(200).
+		BodyString(`[{"jsonrpc":"2.0","id":1,"result":null},{"jsonrpc":"2.0","id":2,"error":{"code":-32602,"message":"Invalid params"}}]`)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no scheduler schedule name cc5"`).Reply(200)
+	_, err = client.CommitConfirm(time.Minute, func() error { t.Fail(); return nil }, CommitName("cc5"))
+	assert.ErrorContains(t, err, `command "feature scheduler" failed: Invalid params`)
+	assert.True(t, gock.IsDone())
+
+	// Creating the checkpoint fails, the scheduler job is removed
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"scheduler job name cc7"`).Reply(200)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint cc7`).Reply(200).
+		BodyString(`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"Invalid params"}}`)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no scheduler schedule name cc7"`).Reply(200)
+	_, err = client.CommitConfirm(time.Minute, func() error { t.Fail(); return nil }, CommitName("cc7"))
+	assert.ErrorContains(t, err, "cannot create checkpoint cc7")
+	assert.True(t, gock.IsDone())
+}
+
+// TestSchedulerDelta tests the schedulerDelta function.
+func TestSchedulerDelta(t *testing.T) {
+	assert.Equal(t, "+1", schedulerDelta(10*time.Second))
+	assert.Equal(t, "+2", schedulerDelta(61*time.Second))
+	assert.Equal(t, "+1:05", schedulerDelta(65*time.Minute))
+	assert.Equal(t, "+1:02:00", schedulerDelta(26*time.Hour))
+	assert.Equal(t, "rollback failed", CommitRollbackFailed.String())
+}
@@ -0,0 +1,329 @@
+// Package diff compares DME managed object trees, e.g. device vs desired state,
+// before vs after a change, or device A vs device B:
+//
+//	before, _ := client.GetDn("sys/intf", nxos.Query("rsp-subtree", "full"))
+//	after, _ := client.GetDn("sys/intf", nxos.Query("rsp-subtree", "full"))
+//	d := diff.Compare(before, after)
+//	fmt.Print(d.Text())
+//
+// Trees are walked in the {"class":{"attributes":{...},"children":[...]}} format.
+// Children are matched by class and RN, which is derived from the dn or rn attribute.
+// Volatile attributes such as modTs are ignored according to configurable rules.
+package diff
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/netascode/go-nxos"
+	"github.com/tidwall/gjson"
+)
+
+// Op is the kind of a difference.
+type Op string
+
+const (
+	Added    Op = "added"
+	Removed  Op = "removed"
+	Modified Op = "modified"
+)
+
+// Attribute is a differing attribute. Old is empty for added objects and attributes,
+// New is empty for removed objects and attributes.
+type Attribute struct {
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// Entry is the difference of a single managed object. Added and removed objects are
+// reported once, including all their non-ignored attributes; their descendants are not
+// reported separately. If an object has neither a dn nor an rn attribute, its Dn is
+// made up of the parent DN and the class name.
+type Entry struct {
+	Op         Op          `json:"op"`
+	Class      string      `json:"class"`
+	Dn         string      `json:"dn"`
+	Attributes []Attribute `json:"attributes,omitempty"`
+}
+
+// Result is the difference of two trees.
+type Result struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Equal reports whether the trees are equal.
+func (r Result) Equal() bool {
+	return len(r.Entries) == 0
+}
+
+// Rule reports whether an attribute of a class is ignored.
+type Rule func(class, attr string) bool
+
+// IgnoreAttributes ignores the given attributes of all classes.
+func IgnoreAttributes(names ...string) Rule {
+	return func(_, attr string) bool {
+		for _, n := range names {
+			if n == attr {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// IgnoreClassAttributes ignores the given attributes of a class.
+func IgnoreClassAttributes(class string, names ...string) Rule {
+	ignore := IgnoreAttributes(names...)
+	return func(c, attr string) bool {
+		return c == class && ignore(c, attr)
+	}
+}
+
+// IgnorePrefix ignores attributes starting with prefix, e.g. "oper" for operational
+// properties such as operSt.
+func IgnorePrefix(prefix string) Rule {
+	return func(_, attr string) bool {
+		return strings.HasPrefix(attr, prefix)
+	}
+}
+
+// DefaultRules ignore volatile attributes, i.e. modification timestamps, status, the
+// persistentOnReload flag and operational properties.
+var DefaultRules = []Rule{
+	IgnoreAttributes("modTs", "status", "childAction", "persistentOnReload"),
+	IgnorePrefix("oper"),
+}
+
+// Options are the options of Compare.
+type Options struct {
+	// Rules decide which attributes are ignored. Defaults to DefaultRules.
+	Rules []Rule
+	// IgnoreClasses are classes whose objects and subtrees are ignored.
+	IgnoreClasses []string
+	// IgnoreMissing ignores attributes and objects missing in b, e.g. if b is a
+	// partial desired state.
+	IgnoreMissing bool
+}
+
+// WithRules adds rules for ignored attributes.
+func WithRules(rules ...Rule) func(*Options) {
+	return func(o *Options) {
+		o.Rules = append(o.Rules, rules...)
+	}
+}
+
+// NoDefaultRules removes the DefaultRules, e.g. to compare modTs.
+// Pass it before WithRules.
+func NoDefaultRules() func(*Options) {
+	return func(o *Options) {
+		o.Rules = nil
+	}
+}
+
+// IgnoreClasses ignores objects of the given classes and their subtrees.
+func IgnoreClasses(classes ...string) func(*Options) {
+	return func(o *Options) {
+		o.IgnoreClasses = append(o.IgnoreClasses, classes...)
+	}
+}
+
+// IgnoreMissing ignores attributes and objects missing in b, e.g. to compare the
+// device (a) with a partial desired state (b).
+func IgnoreMissing() func(*Options) {
+	return func(o *Options) {
+		o.IgnoreMissing = true
+	}
+}
+
+// Compare compares tree a with tree b. Both can be a single object, e.g. as returned
+// by GetDn, or a list of objects, e.g. as returned by GetClass. Entries are ordered
+// depth-first, with objects of a and their children first, followed by added objects.
+func Compare(a, b nxos.Res, opts ...func(*Options)) Result {
+	o := Options{Rules: append([]Rule{}, DefaultRules...)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := comparer{opts: o}
+	c.compareChildren("", objects(a), objects(b))
+	return Result{Entries: c.entries}
+}
+
+// object is a managed object of a tree.
+type object struct {
+	class string
+	body  gjson.Result
+}
+
+func (obj object) rn() nxos.Rn {
+	return nxos.ObjectRn(obj.body)
+}
+
+// dn returns the DN of the object, derived from the parent DN if not given.
+func (obj object) dn(parent nxos.Dn) nxos.Dn {
+	if dn := obj.body.Get("attributes.dn").Str; dn != "" {
+		return nxos.Dn(dn)
+	}
+	if rn := obj.rn(); rn != "" {
+		return parent.Child(rn)
+	}
+	return parent.Child(nxos.Rn(obj.class))
+}
+
+// objects returns the objects of a single object or a list of objects.
+func objects(res gjson.Result) []object {
+	var objs []object
+	items := []gjson.Result{res}
+	if res.IsArray() {
+		items = res.Array()
+	}
+	for _, item := range items {
+		item.ForEach(func(k, v gjson.Result) bool {
+			objs = append(objs, object{class: k.Str, body: v})
+			return true
+		})
+	}
+	return objs
+}
+
+type comparer struct {
+	opts    Options
+	entries []Entry
+}
+
+func (c *comparer) ignoredClass(class string) bool {
+	for _, ic := range c.opts.IgnoreClasses {
+		if ic == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *comparer) ignored(class, attr string) bool {
+	if attr == "dn" || attr == "rn" {
+		return true
+	}
+	for _, rule := range c.opts.Rules {
+		if rule(class, attr) {
+			return true
+		}
+	}
+	return false
+}
+
+// compareChildren matches children of a and b and compares them.
+func (c *comparer) compareChildren(parent nxos.Dn, as, bs []object) {
+	matched := make([]bool, len(bs))
+	for _, a := range as {
+		if c.ignoredClass(a.class) {
+			continue
+		}
+		i := match(a, bs, matched)
+		if i < 0 {
+			if c.opts.IgnoreMissing {
+				continue
+			}
+			c.entries = append(c.entries, Entry{Op: Removed, Class: a.class, Dn: string(a.dn(parent)), Attributes: c.attributes(a, Removed)})
+			continue
+		}
+		matched[i] = true
+		c.compare(parent, a, bs[i])
+	}
+	for i, b := range bs {
+		if matched[i] || c.ignoredClass(b.class) {
+			continue
+		}
+		c.entries = append(c.entries, Entry{Op: Added, Class: b.class, Dn: string(b.dn(parent)), Attributes: c.attributes(b, Added)})
+	}
+}
+
+// compare compares two matched objects and their children.
+func (c *comparer) compare(parent nxos.Dn, a, b object) {
+	dn := a.dn(parent)
+	if a.rn() == "" {
+		dn = b.dn(parent)
+	}
+	aAttrs := a.body.Get("attributes").Map()
+	bAttrs := b.body.Get("attributes").Map()
+	var attrs []Attribute
+	for _, name := range unionKeys(aAttrs, bAttrs) {
+		if c.ignored(a.class, name) {
+			continue
+		}
+		av, bv := aAttrs[name], bAttrs[name]
+		if c.opts.IgnoreMissing && !bv.Exists() {
+			continue
+		}
+		if av.Exists() && bv.Exists() && av.String() == bv.String() {
+			continue
+		}
+		attrs = append(attrs, Attribute{Name: name, Old: av.String(), New: bv.String()})
+	}
+	if len(attrs) > 0 {
+		c.entries = append(c.entries, Entry{Op: Modified, Class: a.class, Dn: string(dn), Attributes: attrs})
+	}
+	c.compareChildren(dn, objects(a.body.Get("children")), objects(b.body.Get("children")))
+}
+
+// attributes returns the non-ignored attributes of an added or removed object.
+func (c *comparer) attributes(obj object, op Op) []Attribute {
+	var attrs []Attribute
+	m := obj.body.Get("attributes").Map()
+	for _, name := range unionKeys(m, nil) {
+		if c.ignored(obj.class, name) {
+			continue
+		}
+		if op == Added {
+			attrs = append(attrs, Attribute{Name: name, New: m[name].String()})
+		} else {
+			attrs = append(attrs, Attribute{Name: name, Old: m[name].String()})
+		}
+	}
+	return attrs
+}
+
+// match returns the index of the unmatched object in bs matching a, or -1.
+// Objects match if they have the same class and RN. If the RN of one object is
+// unknown, it must hold the naming values of the other object's RN in the same
+// naming properties, see nxos.Rn.MatchesAttributes.
+func match(a object, bs []object, matched []bool) int {
+	aRn := a.rn()
+	for i, b := range bs {
+		if matched[i] || b.class != a.class {
+			continue
+		}
+		bRn := b.rn()
+		switch {
+		case aRn != "" && bRn != "":
+			if aRn == bRn {
+				return i
+			}
+		case aRn != "":
+			if aRn.MatchesAttributes(a.body.Get("attributes"), b.body.Get("attributes")) {
+				return i
+			}
+		case bRn != "":
+			if bRn.MatchesAttributes(b.body.Get("attributes"), a.body.Get("attributes")) {
+				return i
+			}
+		default:
+			return i
+		}
+	}
+	return -1
+}
+
+func unionKeys(a, b map[string]gjson.Result) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
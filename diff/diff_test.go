@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+const before = `{"interfaceEntity":{"attributes":{"dn":"sys/intf","modTs":"2024-01-01"},"children":[
+	{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/1]","id":"eth1/1","mtu":"1500","operSt":"up","modTs":"2024-01-01"}}},
+	{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/2]","id":"eth1/2","mtu":"9216"}}},
+	{"l1Loopback":{"attributes":{"dn":"sys/intf/lb-[lo0]","id":"lo0"}}}
+]}}`
+
+const after = `{"interfaceEntity":{"attributes":{"dn":"sys/intf","modTs":"2024-02-01"},"children":[
+	{"l1Loopback":{"attributes":{"dn":"sys/intf/lb-[lo0]","id":"lo0"}}},
+	{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/1]","id":"eth1/1","mtu":"9216","descr":"uplink","operSt":"down","modTs":"2024-02-01"}}},
+	{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/3]","id":"eth1/3"}}}
+]}}`
+
+// TestCompare tests the Compare function.
+func TestCompare(t *testing.T) {
+	d := Compare(gjson.Parse(before), gjson.Parse(after))
+	assert.Equal(t, []Entry{
+		{Op: Modified, Class: "l1PhysIf", Dn: "sys/intf/phys-[eth1/1]", Attributes: []Attribute{
+			{Name: "descr", New: "uplink"},
+			{Name: "mtu", Old: "1500", New: "9216"},
+		}},
+		{Op: Removed, Class: "l1PhysIf", Dn: "sys/intf/phys-[eth1/2]", Attributes: []Attribute{
+			{Name: "id", Old: "eth1/2"},
+			{Name: "mtu", Old: "9216"},
+		}},
+		{Op: Added, Class: "l1PhysIf", Dn: "sys/intf/phys-[eth1/3]", Attributes: []Attribute{
+			{Name: "id", New: "eth1/3"},
+		}},
+	}, d.Entries)
+	assert.False(t, d.Equal())
+	assert.True(t, Compare(gjson.Parse(before), gjson.Parse(before)).Equal())
+}
+
+// TestCompareOptions tests the options of Compare.
+func TestCompareOptions(t *testing.T) {
+	// Custom rules
+	d := Compare(gjson.Parse(before), gjson.Parse(after),
+		WithRules(IgnoreClassAttributes("l1PhysIf", "mtu", "descr")),
+		IgnoreClasses("l1Loopback"))
+	assert.Len(t, d.Entries, 2)
+
+	// Without default rules
+	d = Compare(gjson.Parse(before), gjson.Parse(after), NoDefaultRules(), WithRules(IgnoreAttributes("operSt")))
+	assert.Equal(t, Entry{Op: Modified, Class: "interfaceEntity", Dn: "sys/intf", Attributes: []Attribute{
+		{Name: "modTs", Old: "2024-01-01", New: "2024-02-01"},
+	}}, d.Entries[0])
+
+	// Partial desired state without DNs
+	desired := `{"interfaceEntity":{"attributes":{},"children":[{"l1PhysIf":{"attributes":{"id":"eth1/1","mtu":"9216"}}}]}}`
+	d = Compare(gjson.Parse(before), gjson.Parse(desired), IgnoreMissing())
+	assert.Equal(t, []Entry{{Op: Modified, Class: "l1PhysIf", Dn: "sys/intf/phys-[eth1/1]", Attributes: []Attribute{
+		{Name: "mtu", Old: "1500", New: "9216"},
+	}}}, d.Entries)
+}
+
+// TestCompareList tests Compare with lists of objects, e.g. from GetClass.
+func TestCompareList(t *testing.T) {
+	a := `[{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/1]","mtu":"1500"}}},{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/2]"}}}]`
+	b := `[{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/2]"}}},{"l1PhysIf":{"attributes":{"dn":"sys/intf/phys-[eth1/1]","mtu":"9000"}}}]`
+	d := Compare(gjson.Parse(a), gjson.Parse(b))
+	assert.Equal(t, []Entry{{Op: Modified, Class: "l1PhysIf", Dn: "sys/intf/phys-[eth1/1]", Attributes: []Attribute{
+		{Name: "mtu", Old: "1500", New: "9000"},
+	}}}, d.Entries)
+}
+
+// TestRender tests the Text, JSON and Unified methods.
+func TestRender(t *testing.T) {
+	d := Compare(gjson.Parse(before), gjson.Parse(after))
+	assert.Equal(t, `~ l1PhysIf sys/intf/phys-[eth1/1]
+    descr:  -> uplink
+    mtu: 1500 -> 9216
+- l1PhysIf sys/intf/phys-[eth1/2]
+    id: eth1/2
+    mtu: 9216
++ l1PhysIf sys/intf/phys-[eth1/3]
+    id: eth1/3
+`, d.Text())
+
+	assert.Equal(t, `--- before
++++ after
+@@ l1PhysIf sys/intf/phys-[eth1/1] @@
++descr: uplink
+-mtu: 1500
++mtu: 9216
+@@ l1PhysIf sys/intf/phys-[eth1/2] @@
+-id: eth1/2
+-mtu: 9216
+@@ l1PhysIf sys/intf/phys-[eth1/3] @@
++id: eth1/3
+`, d.Unified("before", "after"))
+
+	b, err := d.JSON()
+	assert.NoError(t, err)
+	assert.Equal(t, "modified", gjson.GetBytes(b, "entries.0.op").Str)
+	assert.Equal(t, "1500", gjson.GetBytes(b, "entries.0.attributes.1.old").Str)
+
+	b, err = Result{}.JSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"entries":[]}`, string(b))
+	assert.Equal(t, "", Result{}.Unified("a", "b"))
+}
@@ -0,0 +1,80 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Text renders the differences, one line per object and attribute, e.g.
+//
+//	~ l1PhysIf sys/intf/phys-[eth1/1]
+//	    mtu: 1500 -> 9216
+//	+ l1PhysIf sys/intf/phys-[eth1/2]
+//	    id: eth1/2
+func (r Result) Text() string {
+	var b strings.Builder
+	for _, e := range r.Entries {
+		fmt.Fprintf(&b, "%s %s %s\n", symbol(e.Op), e.Class, e.Dn)
+		for _, a := range e.Attributes {
+			switch e.Op {
+			case Added:
+				fmt.Fprintf(&b, "    %s: %s\n", a.Name, a.New)
+			case Removed:
+				fmt.Fprintf(&b, "    %s: %s\n", a.Name, a.Old)
+			default:
+				fmt.Fprintf(&b, "    %s: %s -> %s\n", a.Name, a.Old, a.New)
+			}
+		}
+	}
+	return b.String()
+}
+
+// JSON renders the differences as JSON, e.g.
+//
+//	{"entries":[{"op":"modified","class":"l1PhysIf","dn":"sys/intf/phys-[eth1/1]",
+//	  "attributes":[{"name":"mtu","old":"1500","new":"9216"}]}]}
+func (r Result) JSON() ([]byte, error) {
+	if r.Entries == nil {
+		r.Entries = []Entry{}
+	}
+	return json.Marshal(r)
+}
+
+// Unified renders the differences in unified diff style with one hunk per object.
+// The names of the compared trees are given by from and to, e.g.
+//
+//	--- device
+//	+++ desired
+//	@@ l1PhysIf sys/intf/phys-[eth1/1] @@
+//	-mtu: 1500
+//	+mtu: 9216
+func (r Result) Unified(from, to string) string {
+	if r.Equal() {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+	for _, e := range r.Entries {
+		fmt.Fprintf(&b, "@@ %s %s @@\n", e.Class, e.Dn)
+		for _, a := range e.Attributes {
+			if e.Op != Added && (e.Op == Removed || a.Old != "") {
+				fmt.Fprintf(&b, "-%s: %s\n", a.Name, a.Old)
+			}
+			if e.Op != Removed && (e.Op == Added || a.New != "") {
+				fmt.Fprintf(&b, "+%s: %s\n", a.Name, a.New)
+			}
+		}
+	}
+	return b.String()
+}
+
+func symbol(op Op) string {
+	switch op {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	}
+	return "~"
+}
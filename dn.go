@@ -0,0 +1,244 @@
+package nxos
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dn is a distinguished name of a managed object, e.g. "sys/intf/phys-[eth1/1]".
+// A DN consists of relative names (RNs) separated by slashes; slashes within
+// brackets are part of a naming value. A Dn is passed to GetMo, PostMo, PutMo and
+// DeleteMo, e.g.
+//
+//	dn := nxos.NewDn("sys", "bgp", "inst").Child(nxos.NewRn("dom", "default")).Child(nxos.NewRn("peer", "10.0.0.1"))
+//	res, _ := client.GetMo(dn)
+type Dn string
+
+// Rn is a relative name of a managed object, e.g. "phys-[eth1/1]".
+type Rn string
+
+// NewDn creates a DN from RNs.
+func NewDn[T ~string](rns ...T) Dn {
+	parts := make([]string, len(rns))
+	for i, rn := range rns {
+		parts[i] = string(rn)
+	}
+	return Dn(strings.Join(parts, "/"))
+}
+
+// ParseDn parses and validates a DN.
+func ParseDn(s string) (Dn, error) {
+	dn := Dn(s)
+	return dn, dn.Validate()
+}
+
+// Validate checks that the DN is not empty, brackets are balanced and no RN is empty.
+func (dn Dn) Validate() error {
+	if dn == "" {
+		return fmt.Errorf("empty DN")
+	}
+	depth := 0
+	for i, c := range dn {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("invalid DN %q: unexpected ']' at position %d", string(dn), i)
+			}
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("invalid DN %q: unbalanced brackets", string(dn))
+	}
+	for _, rn := range dn.Rns() {
+		if rn == "" {
+			return fmt.Errorf("invalid DN %q: empty RN", string(dn))
+		}
+	}
+	return nil
+}
+
+// String returns the DN as string.
+func (dn Dn) String() string {
+	return string(dn)
+}
+
+// Rns splits the DN into its RNs, ignoring slashes within brackets, e.g.
+// "sys/intf/phys-[eth1/1]" returns "sys", "intf" and "phys-[eth1/1]".
+func (dn Dn) Rns() []Rn {
+	if dn == "" {
+		return nil
+	}
+	var rns []Rn
+	depth := 0
+	start := 0
+	for i := 0; i < len(dn); i++ {
+		switch dn[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '/':
+			if depth == 0 {
+				rns = append(rns, Rn(dn[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(rns, Rn(dn[start:]))
+}
+
+// Rn returns the last RN of the DN.
+func (dn Dn) Rn() Rn {
+	rns := dn.Rns()
+	if len(rns) == 0 {
+		return ""
+	}
+	return rns[len(rns)-1]
+}
+
+// Parent returns the DN of the parent object, or an empty DN for top-level objects.
+func (dn Dn) Parent() Dn {
+	rns := dn.Rns()
+	if len(rns) <= 1 {
+		return ""
+	}
+	return NewDn(rns[:len(rns)-1]...)
+}
+
+// Child returns the DN of a child object with the given RN.
+func (dn Dn) Child(rn Rn) Dn {
+	if dn == "" {
+		return Dn(rn)
+	}
+	return Dn(string(dn) + "/" + string(rn))
+}
+
+// IsAncestorOf reports whether the DN is a proper ancestor of other.
+func (dn Dn) IsAncestorOf(other Dn) bool {
+	rns, otherRns := dn.Rns(), other.Rns()
+	if len(rns) >= len(otherRns) {
+		return false
+	}
+	for i, rn := range rns {
+		if otherRns[i] != rn {
+			return false
+		}
+	}
+	return true
+}
+
+// NewRn creates an RN from a class prefix and naming values, where every value
+// is enclosed in brackets, e.g. NewRn("phys", "eth1/1") returns "phys-[eth1/1]".
+// Without values, the prefix is returned as is, e.g. NewRn("intf") returns "intf".
+func NewRn(prefix string, values ...string) Rn {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	for _, v := range values {
+		sb.WriteString("-[")
+		sb.WriteString(v)
+		sb.WriteString("]")
+	}
+	return Rn(sb.String())
+}
+
+// String returns the RN as string.
+func (rn Rn) String() string {
+	return string(rn)
+}
+
+// Prefix returns the class prefix of the RN, e.g. "phys" for "phys-[eth1/1]"
+// and "ent" for "ent-10".
+func (rn Rn) Prefix() string {
+	if i := strings.IndexAny(string(rn), "-["); i >= 0 {
+		return string(rn[:i])
+	}
+	return string(rn)
+}
+
+// Keys returns the naming values of the RN, e.g. "eth1/1" for "phys-[eth1/1]".
+// Values are either enclosed in brackets or, if there are no brackets, follow the
+// first dash, e.g. "10" for "ent-10".
+func (rn Rn) Keys() []string {
+	var keys []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(rn); i++ {
+		switch rn[i] {
+		case '[':
+			if depth == 0 {
+				start = i + 1
+			}
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				keys = append(keys, string(rn[start:i]))
+			}
+		}
+	}
+	if keys == nil {
+		if _, v, ok := strings.Cut(string(rn), "-"); ok {
+			keys = []string{v}
+		}
+	}
+	return keys
+}
+
+// ObjectRn returns the RN of a managed object, e.g. the content of {"l1PhysIf":{...}},
+// taken from its dn or rn attribute. It is empty if the object has neither.
+func ObjectRn(obj Res) Rn {
+	if dn := obj.Get("attributes.dn").Str; dn != "" {
+		return Dn(dn).Rn()
+	}
+	return Rn(obj.Get("attributes.rn").Str)
+}
+
+// MatchesAttributes reports whether attrs hold the naming values of the object with
+// the RN and the attributes named, e.g. to find the object of a desired body without
+// rn or dn attribute among the children on the device. The naming properties are the
+// attributes of named holding a naming value, e.g. id of an l1PhysIf object with RN
+// phys-[eth1/1]; attrs must hold the same value in at least one and differ in none
+// of them. If named has no such attribute, any attribute of attrs may hold the value.
+func (rn Rn) MatchesAttributes(named, attrs Res) bool {
+	namedAttrs, values := named.Map(), attrs.Map()
+	for _, k := range rn.Keys() {
+		found, naming := false, false
+		for name, v := range namedAttrs {
+			if v.String() != k {
+				continue
+			}
+			naming = true
+			if value, ok := values[name]; ok {
+				if value.String() != k {
+					return false
+				}
+				found = true
+			}
+		}
+		if !naming {
+			for _, v := range values {
+				if v.String() == k {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// dnPathEscaper escapes characters of a DN which would otherwise be interpreted
+// as part of the URL, e.g. spaces in route-map names. Percent signs are kept, so
+// DNs already escaped by the caller are not escaped twice.
+var dnPathEscaper = strings.NewReplacer("?", "%3F", "#", "%23", " ", "%20")
+
+// escapeDn escapes a DN for use in a URL path.
+func escapeDn(dn string) string {
+	return dnPathEscaper.Replace(dn)
+}
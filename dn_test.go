@@ -0,0 +1,97 @@
+package nxos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// TestDn tests the Dn type.
+func TestDn(t *testing.T) {
+	dn := Dn("sys/bgp/inst/dom-[default]/peer-[10.0.0.1/32]")
+	assert.Equal(t, []Rn{"sys", "bgp", "inst", "dom-[default]", "peer-[10.0.0.1/32]"}, dn.Rns())
+	assert.Equal(t, Rn("peer-[10.0.0.1/32]"), dn.Rn())
+	assert.Equal(t, Dn("sys/bgp/inst/dom-[default]"), dn.Parent())
+	assert.Equal(t, Dn(""), Dn("sys").Parent())
+	assert.True(t, Dn("sys/bgp").IsAncestorOf(dn))
+	assert.False(t, dn.IsAncestorOf(dn))
+	assert.False(t, Dn("sys/bgp/in").IsAncestorOf(dn))
+
+	built := NewDn("sys", "bgp", "inst").Child(NewRn("dom", "default")).Child(NewRn("peer", "10.0.0.1/32"))
+	assert.Equal(t, dn, built)
+	assert.Equal(t, Dn("sys"), Dn("").Child("sys"))
+	assert.Equal(t, Dn("sys/intf"), NewDn(Rn("sys"), Rn("intf")))
+
+	// Nested brackets
+	nested := Dn("sys/rpm/rtmap-[map[1]]/ent-10")
+	assert.Len(t, nested.Rns(), 4)
+	assert.NoError(t, nested.Validate())
+}
+
+// TestParseDn tests the ParseDn function.
+func TestParseDn(t *testing.T) {
+	_, err := ParseDn("sys/intf/phys-[eth1/1]")
+	assert.NoError(t, err)
+	_, err = ParseDn("sys/intf/phys-[eth1/1")
+	assert.Error(t, err)
+	_, err = ParseDn("sys/intf]")
+	assert.Error(t, err)
+	_, err = ParseDn("sys//intf")
+	assert.Error(t, err)
+	_, err = ParseDn("")
+	assert.Error(t, err)
+}
+
+// TestRn tests the Rn type.
+func TestRn(t *testing.T) {
+	assert.Equal(t, Rn("phys-[eth1/1]"), NewRn("phys", "eth1/1"))
+	assert.Equal(t, Rn("intf"), NewRn("intf"))
+	assert.Equal(t, "phys", Rn("phys-[eth1/1]").Prefix())
+	assert.Equal(t, []string{"eth1/1"}, Rn("phys-[eth1/1]").Keys())
+	assert.Equal(t, []string{"a", "b"}, NewRn("rt", "a", "b").Keys())
+	assert.Equal(t, "ent", Rn("ent-10").Prefix())
+	assert.Equal(t, []string{"10"}, Rn("ent-10").Keys())
+	assert.Nil(t, Rn("intf").Keys())
+	assert.Equal(t, []string{"map[1]"}, Rn("rtmap-[map[1]]").Keys())
+
+	named := gjson.Parse(`{"id":"eth1/1","descr":"eth1/2"}`)
+	assert.True(t, Rn("phys-[eth1/1]").MatchesAttributes(named, gjson.Parse(`{"id":"eth1/1","mtu":"9216"}`)))
+	assert.False(t, Rn("phys-[eth1/1]").MatchesAttributes(named, gjson.Parse(`{"id":"eth1/3","descr":"eth1/1"}`)))
+	assert.False(t, Rn("phys-[eth1/1]").MatchesAttributes(named, gjson.Parse(`{"mtu":"9216"}`)))
+	assert.True(t, Rn("phys-[eth1/1]").MatchesAttributes(gjson.Parse(`{}`), gjson.Parse(`{"id":"eth1/1"}`)))
+	assert.True(t, Rn("intf").MatchesAttributes(named, gjson.Parse(`{"mtu":"9216"}`)))
+}
+
+// TestObjectRn tests the ObjectRn function.
+func TestObjectRn(t *testing.T) {
+	assert.Equal(t, Rn("phys-[eth1/1]"), ObjectRn(gjson.Parse(`{"attributes":{"dn":"sys/intf/phys-[eth1/1]"}}`)))
+	assert.Equal(t, Rn("phys-[eth1/1]"), ObjectRn(gjson.Parse(`{"attributes":{"rn":"phys-[eth1/1]"}}`)))
+	assert.Equal(t, Rn(""), ObjectRn(gjson.Parse(`{"attributes":{"id":"eth1/1"}}`)))
+}
+
+// TestClientGetDnEscaping tests escaping of special characters in DNs.
+func TestClientGetDnEscaping(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).
+		Get("/api/mo/sys/rpm/rtmap-\\[my map\\?\\].json").
+		Reply(200).
+		BodyString(Body{}.Set("imdata.0.rtctrlRtMap.attributes.name", "my map?").Str)
+	res, err := client.GetMo(NewDn("sys", "rpm").Child(NewRn("rtmap", "my map?")))
+	assert.NoError(t, err)
+	assert.Equal(t, "my map?", res.Get("rtctrlRtMap.attributes.name").Str)
+
+	// DNs escaped by the caller are not escaped twice
+	gock.New(testURL).
+		Delete("/api/mo/sys/rpm/rtmap-\\[my map\\].json").
+		Reply(200)
+	_, err = client.DeleteDn("sys/rpm/rtmap-[my%20map]")
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+
+	assert.Equal(t, "rtmap-[a%20b%3F%23]", escapeDn("rtmap-[a b?#]"))
+	assert.Equal(t, "rtmap-[a%20b%25]", escapeDn("rtmap-[a%20b%25]"))
+}
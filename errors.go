@@ -0,0 +1,130 @@
+package nxos
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors for classifying API errors with errors.Is, e.g.
+//
+//	if errors.Is(err, nxos.ErrNotFound) {
+//	    ...
+//	}
+var (
+	// ErrNotFound indicates that the addressed object does not exist.
+	ErrNotFound = errors.New("object not found")
+	// ErrFeatureNotEnabled indicates that the required NX-OS feature is disabled.
+	ErrFeatureNotEnabled = errors.New("feature not enabled")
+	// ErrInvalidArgument indicates that the request was rejected as malformed or invalid.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrRetryable indicates a transient failure which may succeed when retried.
+	ErrRetryable = errors.New("retryable error")
+)
+
+// AuthenticationError indicates that the device was reachable but
+// rejected the supplied credentials (HTTP 401/403).
+type AuthenticationError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *AuthenticationError) Error() string {
+	return fmt.Sprintf("authentication failed (HTTP %d): %s", e.StatusCode, e.Message)
+}
+
+// APIError is returned when the device responds with an NX-API error object,
+// i.e. imdata.0.error, or a server error status after all retries are exhausted.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Code is the NX-API error code, e.g. "1" or "107".
+	Code string
+	// Text is the NX-API error text.
+	Text string
+	// Method is the HTTP method of the request.
+	Method string
+	// Dn is the DN or class addressed by the request, or the URL path for other endpoints.
+	Dn string
+	// Raw is the raw response body.
+	Raw string
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("HTTP Request failed: StatusCode %d (%s %s)", e.StatusCode, e.Method, e.Dn)
+	}
+	return fmt.Sprintf("JSON error (HTTP %d) %s %s: code %s: %s", e.StatusCode, e.Method, e.Dn, e.Code, e.Text)
+}
+
+// Is reports whether the error matches one of the sentinel errors.
+func (e *APIError) Is(target error) bool {
+	text := strings.ToLower(e.Text)
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == 404 ||
+			strings.Contains(text, "not found") ||
+			strings.Contains(text, "unable to find") ||
+			strings.Contains(text, "does not exist")
+	case ErrFeatureNotEnabled:
+		return isFeatureNotEnabledText(e.Text)
+	case ErrInvalidArgument:
+		return e.StatusCode == 400 && !e.Is(ErrNotFound) && !e.Is(ErrFeatureNotEnabled)
+	case ErrRetryable:
+		return (e.StatusCode >= 500 && e.StatusCode <= 504) ||
+			e.StatusCode == 405 ||
+			strings.Contains(text, "busy") ||
+			strings.Contains(text, "try again")
+	}
+	return false
+}
+
+// isFeatureNotEnabledText reports whether an error text indicates that a required
+// feature is disabled, e.g. "Feature bgp not enabled".
+func isFeatureNotEnabledText(text string) bool {
+	text = strings.ToLower(text)
+	return strings.Contains(text, "feature") &&
+		(strings.Contains(text, "not enabled") || strings.Contains(text, "disabled"))
+}
+
+// newAPIError creates an APIError from a response.
+func newAPIError(statusCode int, method, path string, res Res) *APIError {
+	return &APIError{
+		StatusCode: statusCode,
+		Code:       res.Get("imdata.0.error.attributes.code").Str,
+		Text:       res.Get("imdata.0.error.attributes.text").Str,
+		Method:     method,
+		Dn:         dnFromPath(path),
+		Raw:        res.Raw,
+	}
+}
+
+// dnFromPath extracts the DN or class from an API URL path.
+func dnFromPath(path string) string {
+	for _, prefix := range []string{"/api/mo/", "/api/class/"} {
+		if strings.HasPrefix(path, prefix) {
+			return strings.TrimSuffix(strings.TrimPrefix(path, prefix), ".json")
+		}
+	}
+	return path
+}
+
+// IsNotFound reports whether err indicates that the addressed object does not exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsFeatureNotEnabled reports whether err indicates that a required feature is disabled.
+func IsFeatureNotEnabled(err error) bool {
+	return errors.Is(err, ErrFeatureNotEnabled)
+}
+
+// IsInvalidArgument reports whether err indicates a malformed or invalid request.
+func IsInvalidArgument(err error) bool {
+	return errors.Is(err, ErrInvalidArgument)
+}
+
+// IsRetryable reports whether err indicates a transient failure.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRetryable)
+}
@@ -0,0 +1,57 @@
+package nxos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// TestAPIError tests the APIError returned by Client::Do.
+func TestAPIError(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).
+		Post("/api/mo/sys/bgp/inst.json").
+		Reply(400).
+		BodyString(Body{}.
+			Set("imdata.0.error.attributes.code", "1").
+			Set("imdata.0.error.attributes.text", "Feature bgp is not enabled").
+			Str)
+	_, err := client.Post("sys/bgp/inst", "{}")
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 400, apiErr.StatusCode)
+	assert.Equal(t, "1", apiErr.Code)
+	assert.Equal(t, "POST", apiErr.Method)
+	assert.Equal(t, "sys/bgp/inst", apiErr.Dn)
+	assert.True(t, IsFeatureNotEnabled(err))
+	assert.False(t, IsInvalidArgument(err))
+	assert.False(t, IsRetryable(err))
+
+	// Server error after retries are exhausted
+	gock.New(testURL).Get("/api/class/l1PhysIf.json").Reply(503)
+	_, err = client.GetClass("l1PhysIf")
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 503, apiErr.StatusCode)
+	assert.Equal(t, "l1PhysIf", apiErr.Dn)
+	assert.True(t, IsRetryable(err))
+}
+
+// TestAPIErrorIs tests the APIError::Is method.
+func TestAPIErrorIs(t *testing.T) {
+	notFound := &APIError{StatusCode: 400, Code: "1", Text: "Unable to find MO"}
+	assert.True(t, IsNotFound(notFound))
+	assert.False(t, IsInvalidArgument(notFound))
+
+	invalid := &APIError{StatusCode: 400, Code: "107", Text: "Invalid value for property mtu"}
+	assert.True(t, IsInvalidArgument(invalid))
+	assert.False(t, IsNotFound(invalid))
+
+	busy := &APIError{StatusCode: 200, Code: "1", Text: "System busy, try again later"}
+	assert.True(t, IsRetryable(busy))
+
+	assert.False(t, IsNotFound(errors.New("fail")))
+}
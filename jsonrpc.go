@@ -0,0 +1,251 @@
+package nxos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JsonRpcRollback is the NX-API error handling mode of a JSON-RPC request with multiple commands.
+type JsonRpcRollback string
+
+const (
+	// StopOnError stops at the first failed command, keeping the changes of previous commands.
+	// This is the device default.
+	StopOnError JsonRpcRollback = "stop-on-error"
+	// ContinueOnError skips failed commands and runs all remaining commands.
+	ContinueOnError JsonRpcRollback = "continue-on-error"
+	// RollbackOnError stops at the first failed command and rolls back the changes of
+	// previous commands, making the request all-or-nothing.
+	RollbackOnError JsonRpcRollback = "rollback-on-error"
+)
+
+// Rollback sets the error handling mode of a JSON-RPC request, e.g.
+//
+//	client.JsonRpc([]string{"conf t", "interface eth1/1", "mtu 9216"}, nxos.Rollback(nxos.RollbackOnError))
+func Rollback(mode JsonRpcRollback) func(*Req) {
+	return func(req *Req) {
+		req.Rollback = mode
+	}
+}
+
+// JsonRpcResult is the result of a single command of a JSON-RPC request.
+type JsonRpcResult struct {
+	// Id is the id of the request entry, i.e. the position of the command starting at 1.
+	Id int
+	// Command is the command.
+	Command string
+	// Body is the structured output of the command, i.e. result.body.
+	Body Res
+	// Msg is the text output of the command, i.e. result.msg.
+	Msg string
+	// Error is the error of the command, or nil if the command succeeded.
+	Error *JsonRpcError
+}
+
+// JsonRpcError is a JSON-RPC error object of a failed command or request. Command is
+// empty if the error cannot be attributed to a command, e.g. for a malformed request.
+type JsonRpcError struct {
+	// Id is the id of the request entry, or 0 if the error refers to the whole request.
+	Id int
+	// Command is the failed command.
+	Command string
+	// Code is the JSON-RPC error code, e.g. -32602.
+	Code int
+	// Message is the error message, e.g. "Invalid params".
+	Message string
+	// Data is the additional error data, e.g. {"msg":"% Invalid command\n"}.
+	Data Res
+}
+
+func (e *JsonRpcError) Error() string {
+	msg := e.Message
+	if data := strings.TrimSpace(e.Data.Get("msg").Str); data != "" {
+		msg += ": " + data
+	}
+	switch {
+	case e.Command != "":
+		return fmt.Sprintf("command %q failed: %s", e.Command, msg)
+	case e.Id > 0:
+		return fmt.Sprintf("command %d failed: %s", e.Id, msg)
+	}
+	return fmt.Sprintf("JSON-RPC request failed: %s", msg)
+}
+
+// Is reports whether the error matches one of the sentinel errors.
+func (e *JsonRpcError) Is(target error) bool {
+	switch target {
+	case ErrFeatureNotEnabled:
+		return isFeatureNotEnabledText(e.Message + " " + e.Data.Get("msg").Str)
+	case ErrInvalidArgument:
+		// Parse error, invalid request and invalid params
+		return e.Code == -32700 || e.Code == -32600 || e.Code == -32602
+	}
+	return false
+}
+
+// JsonRpc makes a JSON-RPC request with one or more commands and returns a GJSON result.
+// If a command fails, the result is returned together with a *JsonRpcError of the first
+// failed command. Use JsonRpcResults for typed results of all commands.
+func (client *Client) JsonRpc(commands []string, mods ...func(*Req)) (Res, error) {
+	return client.JsonRpcCtx(context.Background(), commands, mods...)
+}
+
+// JsonRpcCtx makes a JSON-RPC request using the given context.
+// See JsonRpc for details.
+func (client *Client) JsonRpcCtx(ctx context.Context, commands []string, mods ...func(*Req)) (Res, error) {
+	return client.jsonRpc(ctx, "cli", commands, mods...)
+}
+
+// JsonRpcResults makes a JSON-RPC request and returns a typed result per command.
+// If a command fails, the results are returned together with a *JsonRpcError of the
+// first failed command, e.g.
+//
+//	results, err := client.JsonRpcResults([]string{"conf t", "interface eth1/1", "mtu 9216"})
+//	var rpcErr *nxos.JsonRpcError
+//	if errors.As(err, &rpcErr) {
+//	    fmt.Println(rpcErr.Command, rpcErr.Code, rpcErr.Message)
+//	}
+func (client *Client) JsonRpcResults(commands []string, mods ...func(*Req)) ([]JsonRpcResult, error) {
+	return client.JsonRpcResultsCtx(context.Background(), commands, mods...)
+}
+
+// JsonRpcResultsCtx makes a JSON-RPC request using the given context and returns a typed result per command.
+// See JsonRpcResults for details.
+func (client *Client) JsonRpcResultsCtx(ctx context.Context, commands []string, mods ...func(*Req)) ([]JsonRpcResult, error) {
+	res, err := client.jsonRpc(ctx, "cli", commands, mods...)
+	return jsonRpcResults(res, commands), err
+}
+
+// jsonRpc makes a JSON-RPC request using the given method, e.g. cli or cli_ascii.
+func (client *Client) jsonRpc(ctx context.Context, method string, commands []string, mods ...func(*Req)) (_ Res, err error) {
+	ctx, span := client.tracer().Start(ctx, "nxos.JsonRpc",
+		trace.WithAttributes(attribute.Int(AttrCommands, len(commands))))
+	defer func() { endSpan(span, err) }()
+
+	// The body depends on the rollback mode set by the request modifiers
+	req := client.NewReqCtx(ctx, "POST", "/ins", nil, mods...)
+	data := jsonRpcBody(method, commands, req.Rollback)
+	req.HttpReq.Body = io.NopCloser(strings.NewReader(data))
+	req.HttpReq.ContentLength = int64(len(data))
+	req.HttpReq.Header.Add("Content-Type", "application/json-rpc")
+	req.HttpReq.Header.Add("Cache-Control", "no-cache")
+	req.HttpReq.SetBasicAuth(client.Usr, client.Pwd)
+	client.cliRequest(&req, hasJsonRpcError)
+	res, err := client.DoCtx(ctx, req)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		// NX-API reports failed commands with a server error status
+		if raw := gjson.Parse(apiErr.Raw); hasJsonRpcError(raw) {
+			res, err = raw, nil
+		}
+	}
+	if err != nil {
+		return res, err
+	}
+	for _, result := range jsonRpcResults(res, commands) {
+		if result.Error != nil {
+			return res, result.Error
+		}
+	}
+	return res, nil
+}
+
+// jsonRpcBody builds the request entries of a JSON-RPC request.
+func jsonRpcBody(method string, commands []string, rollback JsonRpcRollback) string {
+	data := "[]"
+	for i, cmd := range commands {
+		prefix := fmt.Sprintf("%d", i)
+		data, _ = sjson.Set(data, prefix+".jsonrpc", "2.0")
+		data, _ = sjson.Set(data, prefix+".method", method)
+		data, _ = sjson.Set(data, prefix+".params.cmd", cmd)
+		data, _ = sjson.Set(data, prefix+".params.version", 1)
+		data, _ = sjson.Set(data, prefix+".id", i+1)
+		if rollback != "" {
+			data, _ = sjson.Set(data, prefix+".rollback", string(rollback))
+		}
+	}
+	return data
+}
+
+// cliRequest prepares a CLI request whose responses are classified with failed, so that
+// failed commands are neither retried nor recorded as device failures by the circuit breaker.
+func (client *Client) cliRequest(req *Req, failed func(Res) bool) {
+	req.RetryPolicy = cliRetryPolicy(client.retryPolicy(*req), failed)
+	req.commandFailed = failed
+}
+
+// cliRetryPolicy wraps a retry policy so that failed commands are not retried.
+func cliRetryPolicy(policy RetryPolicy, failed func(Res) bool) RetryPolicy {
+	return RetryPolicyFunc(func(attempt RetryAttempt) (bool, time.Duration) {
+		var apiErr *APIError
+		if errors.As(attempt.Err, &apiErr) && failed(gjson.Parse(apiErr.Raw)) {
+			return false, 0
+		}
+		return policy.Retry(attempt)
+	})
+}
+
+// jsonRpcEntries returns the response entries, which is a single object for a single command.
+func jsonRpcEntries(res Res) []Res {
+	if !res.Exists() {
+		return nil
+	}
+	if res.IsArray() {
+		return res.Array()
+	}
+	return []Res{res}
+}
+
+// hasJsonRpcError reports whether any response entry contains an error object.
+func hasJsonRpcError(res Res) bool {
+	for _, entry := range jsonRpcEntries(res) {
+		if entry.Get("error").Exists() {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonRpcResults converts the response entries to typed results. Entries are
+// attributed to commands by their id, or by position if the id is missing.
+func jsonRpcResults(res Res, commands []string) []JsonRpcResult {
+	entries := jsonRpcEntries(res)
+	if len(entries) == 0 {
+		return nil
+	}
+	results := make([]JsonRpcResult, 0, len(entries))
+	for i, entry := range entries {
+		result := JsonRpcResult{
+			Id:   int(entry.Get("id").Int()),
+			Body: entry.Get("result.body"),
+			Msg:  entry.Get("result.msg").Str,
+		}
+		index := result.Id - 1
+		if result.Id == 0 && len(entries) == len(commands) {
+			index = i
+		}
+		if index >= 0 && index < len(commands) {
+			result.Command = commands[index]
+		}
+		if e := entry.Get("error"); e.Exists() {
+			result.Error = &JsonRpcError{
+				Id:      result.Id,
+				Command: result.Command,
+				Code:    int(e.Get("code").Int()),
+				Message: e.Get("message").Str,
+				Data:    e.Get("data"),
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
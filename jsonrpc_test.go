@@ -0,0 +1,90 @@
+package nxos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const testJsonRpcFailed = `[
+  {"jsonrpc": "2.0", "result": null, "id": 1},
+  {"jsonrpc": "2.0", "error": {"code": -32602, "message": "Invalid params", "data": {"msg": "% Invalid command\n"}}, "id": 2}
+]`
+
+// TestClientJsonRpcResults tests the Client::JsonRpcResults method.
+func TestClientJsonRpcResults(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	// Success
+	gock.New(testURL).Post("/ins").
+		Reply(200).
+		BodyString(`[{"jsonrpc":"2.0","result":null,"id":1},{"jsonrpc":"2.0","result":{"body":{"nxos_ver_str":"10.3(2)"}},"id":2}]`)
+	results, err := client.JsonRpcResults([]string{"conf t", "show version"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 1, results[0].Id)
+	assert.Equal(t, "conf t", results[0].Command)
+	assert.Nil(t, results[0].Error)
+	assert.Equal(t, "show version", results[1].Command)
+	assert.Equal(t, "10.3(2)", results[1].Body.Get("nxos_ver_str").Str)
+
+	// Failed command
+	gock.New(testURL).Post("/ins").Reply(200).BodyString(testJsonRpcFailed)
+	results, err = client.JsonRpcResults([]string{"conf t", "interface foo"})
+	assert.EqualError(t, err, `command "interface foo" failed: Invalid params: % Invalid command`)
+	assert.Len(t, results, 2)
+	assert.Nil(t, results[0].Error)
+	assert.Equal(t, &JsonRpcError{Id: 2, Command: "interface foo", Code: -32602, Message: "Invalid params", Data: results[1].Error.Data}, results[1].Error)
+	assert.Equal(t, "% Invalid command\n", results[1].Error.Data.Get("msg").Str)
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	// Top-level error
+	gock.New(testURL).Post("/ins").
+		Reply(200).
+		BodyString(`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`)
+	results, err = client.JsonRpcResults([]string{"conf t", "show version"})
+	assert.EqualError(t, err, "JSON-RPC request failed: Parse error")
+	assert.Len(t, results, 1)
+	assert.Equal(t, "", results[0].Error.Command)
+}
+
+// TestClientJsonRpcError tests error detection of the Client::JsonRpc method.
+func TestClientJsonRpcError(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	client.MaxRetries = 2
+
+	// Failed commands reported with a server error are not retried
+	gock.New(testURL).Post("/ins").Times(1).Reply(500).BodyString(testJsonRpcFailed)
+	res, err := client.JsonRpc([]string{"conf t", "interface foo"})
+	var rpcErr *JsonRpcError
+	assert.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "interface foo", rpcErr.Command)
+	assert.Equal(t, "Invalid params", res.Get("1.error.message").Str)
+	assert.True(t, gock.IsDone())
+
+	// Single command
+	gock.New(testURL).Post("/ins").
+		Reply(200).
+		BodyString(`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":{"msg":"Feature not enabled\n"}},"id":1}`)
+	_, err = client.JsonRpc([]string{"router bgp 65000"})
+	assert.EqualError(t, err, `command "router bgp 65000" failed: Invalid params: Feature not enabled`)
+	assert.ErrorIs(t, err, ErrFeatureNotEnabled)
+}
+
+// TestClientJsonRpcRollback tests the Rollback request modifier.
+func TestClientJsonRpcRollback(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).Post("/ins").
+		BodyString(`"id":1,"rollback":"rollback-on-error"}.*"id":2,"rollback":"rollback-on-error"}`).
+		Reply(200)
+	_, err := client.JsonRpc([]string{"conf t", "interface loopback1"}, Rollback(RollbackOnError))
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+
+	assert.NotContains(t, jsonRpcBody("cli", []string{"show version"}, ""), "rollback")
+}
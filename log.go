@@ -0,0 +1,120 @@
+package nxos
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+)
+
+// LevelTrace is the log level used for very verbose output, e.g. backoff timing.
+const LevelTrace = slog.LevelDebug - 4
+
+// Logger sets the structured logger used by the client.
+// By default all messages are written through the standard library log package
+// using the "[LEVEL] message key=value ..." format. Pass a logger with a level
+// above slog.LevelDebug to suppress request and response payload logging, e.g.
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+//	client, _ := NewClient("1.1.1.1", "user", "pwd", true, Logger(logger))
+func Logger(logger *slog.Logger) func(*Client) {
+	return func(client *Client) {
+		client.Logger = logger
+	}
+}
+
+// defaultLogger is used when no logger has been configured on the client.
+var defaultLogger = slog.New(&logHandler{})
+
+// logger returns the configured logger or the default logger.
+func (client *Client) logger() *slog.Logger {
+	if client.Logger == nil {
+		return defaultLogger
+	}
+	return client.Logger
+}
+
+// debugEnabled reports whether debug messages are emitted by the client logger.
+// This is used to skip expensive payload formatting.
+func (client *Client) debugEnabled(ctx context.Context) bool {
+	return client.logger().Enabled(ctx, slog.LevelDebug)
+}
+
+// logHandler is a slog.Handler writing records through the standard library
+// log package, preserving the "[DEBUG] ..." style output of earlier versions.
+type logHandler struct {
+	attrs  []slog.Attr
+	prefix string
+}
+
+// Enabled implements slog.Handler. All levels are enabled.
+func (h *logHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle implements slog.Handler.
+func (h *logHandler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString("[")
+	sb.WriteString(levelName(r.Level))
+	sb.WriteString("] ")
+	sb.WriteString(r.Message)
+	for _, a := range h.attrs {
+		writeAttr(&sb, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&sb, h.prefix, a)
+		return true
+	})
+	log.Print(sb.String())
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := &logHandler{prefix: h.prefix}
+	n.attrs = append(n.attrs, h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		n.attrs = append(n.attrs, a)
+	}
+	return n
+}
+
+// WithGroup implements slog.Handler.
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &logHandler{attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+func writeAttr(sb *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			writeAttr(sb, prefix+a.Key+".", ga)
+		}
+		return
+	}
+	fmt.Fprintf(sb, " %s%s=%v", prefix, a.Key, a.Value.Any())
+}
+
+func levelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return "TRACE"
+	case level < slog.LevelInfo:
+		return "DEBUG"
+	case level < slog.LevelWarn:
+		return "INFO"
+	case level < slog.LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
@@ -0,0 +1,54 @@
+package nxos
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// TestLogger tests the Logger modifier.
+func TestLogger(t *testing.T) {
+	defer gock.Off()
+	var buf bytes.Buffer
+	client := testClient()
+	Logger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))(client)
+
+	gock.New(testURL).Get("/url.json").Reply(200).BodyString(`{"imdata":[]}`)
+	_, err := client.Get("/url")
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "device="+testURL)
+	assert.Contains(t, buf.String(), "method=GET")
+	assert.Contains(t, buf.String(), "status=200")
+	assert.Contains(t, buf.String(), "payload=")
+
+	// Payloads are not logged when debug is disabled
+	buf.Reset()
+	Logger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))(client)
+	gock.New(testURL).Get("/url.json").Reply(200).BodyString(`{"imdata":[]}`)
+	_, err = client.Get("/url")
+	assert.NoError(t, err)
+	assert.Empty(t, buf.String())
+}
+
+// TestLogHandler tests the default log handler.
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+
+	logger := slog.New(&logHandler{}).With(slog.String("device", "a"))
+	logger.Debug("HTTP Request", slog.Int("attempt", 1))
+	assert.Equal(t, "[DEBUG] HTTP Request device=a attempt=1\n", buf.String())
+
+	buf.Reset()
+	logger.WithGroup("req").Log(context.Background(), LevelTrace, "Sleeping", slog.String("delay", "1s"))
+	assert.Equal(t, "[TRACE] Sleeping device=a req.delay=1s\n", buf.String())
+}
@@ -0,0 +1,421 @@
+package nxos
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// TimeLayout is the layout used to marshal time.Time attributes, e.g. modTs.
+const TimeLayout = "2006-01-02T15:04:05.000-07:00"
+
+// Marshal converts a struct into the managed object format used by Post and Put, i.e.
+// {"class":{"attributes":{...},"children":[...]}}.
+//
+// The class is declared with a blank field tagged nxos:"class=...". Attributes are
+// declared with nxos:"name" tags, optionally followed by options:
+//
+//   - omitempty: omit the attribute if it has the zero value
+//   - readonly: only decode the attribute, e.g. for operational state
+//   - bool=true/false: representation of booleans, e.g. bool=enabled/disabled (default yes/no)
+//   - time=layout: layout of time.Time attributes (default TimeLayout)
+//
+// Child objects are declared with nxos:",children" on struct, struct pointer or
+// slice fields, e.g.
+//
+//	type PhysIf struct {
+//	    _      struct{} `nxos:"class=l1PhysIf"`
+//	    Id     string   `nxos:"id"`
+//	    Mtu    int      `nxos:"mtu,omitempty"`
+//	    Layer3 bool     `nxos:"layer,bool=Layer3/Layer2"`
+//	}
+//
+//	type IntfEntity struct {
+//	    _     struct{} `nxos:"class=interfaceEntity"`
+//	    Phys  []PhysIf `nxos:",children"`
+//	}
+//
+// Strings, booleans, integers, floats, time.Time and types implementing
+// encoding.TextMarshaler are supported as attributes. Nil pointers are omitted.
+func Marshal(v any) (Body, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return Body{}, fmt.Errorf("cannot marshal nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return Body{}, fmt.Errorf("cannot marshal %s, expected struct", rv.Type())
+	}
+	return marshalStruct(rv)
+}
+
+// Unmarshal decodes a managed object result, e.g. as returned by GetDn, into a struct,
+// or a list of managed objects, e.g. as returned by GetClass, into a pointer to a slice
+// of structs or struct pointers. See Marshal for the supported struct tags.
+func Unmarshal(res Res, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("cannot unmarshal into %T, expected non-nil pointer", v)
+	}
+	rv = rv.Elem()
+	if rv.Kind() == reflect.Slice {
+		elemType := rv.Type().Elem()
+		info, err := structInfoOf(elemType)
+		if err != nil {
+			return err
+		}
+		slice := reflect.MakeSlice(rv.Type(), 0, len(res.Array()))
+		for _, item := range res.Array() {
+			obj := item.Get(gjsonEscape(info.class))
+			if !obj.Exists() {
+				continue
+			}
+			elem := reflect.New(elemType).Elem()
+			target := elem
+			if elemType.Kind() == reflect.Pointer {
+				elem = reflect.New(elemType.Elem())
+				target = elem.Elem()
+			}
+			if err := unmarshalStruct(obj, target); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		rv.Set(slice)
+		return nil
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot unmarshal into %T, expected pointer to struct or slice", v)
+	}
+	info, err := structInfoOf(rv.Type())
+	if err != nil {
+		return err
+	}
+	obj := res.Get(gjsonEscape(info.class))
+	if !obj.Exists() {
+		return fmt.Errorf("object of class %s not found", info.class)
+	}
+	return unmarshalStruct(obj, rv)
+}
+
+// fieldInfo describes a tagged struct field.
+type fieldInfo struct {
+	index     int
+	name      string
+	children  bool
+	omitempty bool
+	readonly  bool
+	boolTrue  string
+	boolFalse string
+	layout    string
+}
+
+// structInfo describes a tagged struct type.
+type structInfo struct {
+	class  string
+	fields []fieldInfo
+}
+
+func structInfoOf(t reflect.Type) (structInfo, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return structInfo{}, fmt.Errorf("%s is not a struct", t)
+	}
+	info := structInfo{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("nxos")
+		if !ok || tag == "-" {
+			continue
+		}
+		if f.Name == "_" {
+			if class, ok := strings.CutPrefix(tag, "class="); ok {
+				info.class = class
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		fi := fieldInfo{index: i, name: parts[0], boolTrue: "yes", boolFalse: "no", layout: TimeLayout}
+		if fi.name == "" {
+			fi.name = f.Name
+		}
+		for _, opt := range parts[1:] {
+			switch {
+			case opt == "children":
+				fi.children = true
+			case opt == "omitempty":
+				fi.omitempty = true
+			case opt == "readonly":
+				fi.readonly = true
+			case strings.HasPrefix(opt, "bool="):
+				t, f, ok := strings.Cut(strings.TrimPrefix(opt, "bool="), "/")
+				if !ok {
+					return structInfo{}, fmt.Errorf("invalid bool option %q, expected bool=true/false", opt)
+				}
+				fi.boolTrue, fi.boolFalse = t, f
+			case strings.HasPrefix(opt, "time="):
+				fi.layout = strings.TrimPrefix(opt, "time=")
+			default:
+				return structInfo{}, fmt.Errorf("unknown option %q of field %s", opt, f.Name)
+			}
+		}
+		info.fields = append(info.fields, fi)
+	}
+	if info.class == "" {
+		return structInfo{}, fmt.Errorf("%s has no class, add a field `_ struct{} `nxos:\"class=...\"``", t)
+	}
+	return info, nil
+}
+
+func marshalStruct(rv reflect.Value) (Body, error) {
+	info, err := structInfoOf(rv.Type())
+	if err != nil {
+		return Body{}, err
+	}
+	class := gjsonEscape(info.class)
+	body := Body{}.SetRaw(class+".attributes", "{}")
+	for _, fi := range info.fields {
+		fv := rv.Field(fi.index)
+		if fi.children {
+			children, err := marshalChildren(fv)
+			if err != nil {
+				return Body{}, err
+			}
+			for _, child := range children {
+				body = body.SetRaw(class+".children.-1", child.Str)
+			}
+			continue
+		}
+		if fi.readonly || fi.omitempty && fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		s, err := formatValue(fv, fi)
+		if err != nil {
+			return Body{}, fmt.Errorf("%s.%s: %w", info.class, fi.name, err)
+		}
+		body = body.Set(class+".attributes."+gjsonEscape(fi.name), s)
+	}
+	return body, nil
+}
+
+func marshalChildren(fv reflect.Value) ([]Body, error) {
+	switch fv.Kind() {
+	case reflect.Pointer:
+		if fv.IsNil() {
+			return nil, nil
+		}
+		return marshalChildren(fv.Elem())
+	case reflect.Struct:
+		b, err := marshalStruct(fv)
+		if err != nil {
+			return nil, err
+		}
+		return []Body{b}, nil
+	case reflect.Slice:
+		var bodies []Body
+		for i := 0; i < fv.Len(); i++ {
+			b, err := marshalChildren(fv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			bodies = append(bodies, b...)
+		}
+		return bodies, nil
+	}
+	return nil, fmt.Errorf("unsupported children type %s", fv.Type())
+}
+
+var (
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	timeType            = reflect.TypeFor[time.Time]()
+)
+
+func formatValue(fv reflect.Value, fi fieldInfo) (string, error) {
+	if fv.Type() == timeType {
+		return fv.Interface().(time.Time).Format(fi.layout), nil
+	}
+	if fv.Type().Implements(textMarshalerType) {
+		b, err := fv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		if fv.Bool() {
+			return fi.boolTrue, nil
+		}
+		return fi.boolFalse, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", fv.Type())
+}
+
+func unmarshalStruct(obj gjson.Result, rv reflect.Value) error {
+	info, err := structInfoOf(rv.Type())
+	if err != nil {
+		return err
+	}
+	attrs := obj.Get("attributes")
+	for _, fi := range info.fields {
+		fv := rv.Field(fi.index)
+		if fi.children {
+			if err := unmarshalChildren(obj.Get("children"), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		attr := attrs.Get(gjsonEscape(fi.name))
+		if !attr.Exists() {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer {
+			fv.Set(reflect.New(fv.Type().Elem()))
+			fv = fv.Elem()
+		}
+		if err := parseValue(attr.String(), fv, fi); err != nil {
+			return fmt.Errorf("%s.%s: %w", info.class, fi.name, err)
+		}
+	}
+	return nil
+}
+
+func unmarshalChildren(children gjson.Result, fv reflect.Value) error {
+	elemType := fv.Type()
+	if elemType.Kind() == reflect.Slice {
+		elemType = elemType.Elem()
+	}
+	info, err := structInfoOf(elemType)
+	if err != nil {
+		return err
+	}
+	class := gjsonEscape(info.class)
+	objs := children.Array()
+	// Replace rather than extend or keep children of a reused value
+	if fv.Kind() == reflect.Slice {
+		fv.Set(reflect.MakeSlice(fv.Type(), 0, len(objs)))
+	} else {
+		fv.Set(reflect.Zero(fv.Type()))
+	}
+	for _, child := range objs {
+		obj := child.Get(class)
+		if !obj.Exists() {
+			continue
+		}
+		switch fv.Kind() {
+		case reflect.Slice:
+			elem := reflect.New(elemType).Elem()
+			target := elem
+			if elemType.Kind() == reflect.Pointer {
+				elem = reflect.New(elemType.Elem())
+				target = elem.Elem()
+			}
+			if err := unmarshalStruct(obj, target); err != nil {
+				return err
+			}
+			fv.Set(reflect.Append(fv, elem))
+		case reflect.Pointer:
+			fv.Set(reflect.New(elemType.Elem()))
+			return unmarshalStruct(obj, fv.Elem())
+		case reflect.Struct:
+			return unmarshalStruct(obj, fv)
+		}
+	}
+	return nil
+}
+
+func parseValue(s string, fv reflect.Value, fi fieldInfo) error {
+	if fv.Type() == timeType {
+		t, err := time.Parse(fi.layout, s)
+		if err != nil {
+			t, err = time.Parse(time.RFC3339Nano, s)
+		}
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if reflect.PointerTo(fv.Type()).Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := parseBool(s, fi)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// parseBool parses a boolean using the configured representation or common DME values.
+func parseBool(s string, fi fieldInfo) (bool, error) {
+	switch s {
+	case fi.boolTrue:
+		return true, nil
+	case fi.boolFalse:
+		return false, nil
+	}
+	switch strings.ToLower(s) {
+	case "yes", "true", "enabled", "up", "on":
+		return true, nil
+	case "no", "false", "disabled", "down", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", s)
+}
+
+// gjsonEscape escapes a key for use in GJSON/SJSON paths.
+func gjsonEscape(k string) string {
+	return gjsonKeyEscaper.Replace(k)
+}
+
+var gjsonKeyEscaper = strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`, "|", `\|`, "#", `\#`, "@", `\@`)
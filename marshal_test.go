@@ -0,0 +1,152 @@
+package nxos
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+)
+
+type testAdminSt int
+
+func (s testAdminSt) MarshalText() ([]byte, error) {
+	switch s {
+	case 1:
+		return []byte("up"), nil
+	case 2:
+		return []byte("down"), nil
+	}
+	return nil, fmt.Errorf("invalid admin state %d", s)
+}
+
+func (s *testAdminSt) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "up":
+		*s = 1
+	case "down":
+		*s = 2
+	default:
+		return fmt.Errorf("invalid admin state %q", b)
+	}
+	return nil
+}
+
+type testPhysIf struct {
+	_       struct{}    `nxos:"class=l1PhysIf"`
+	Id      string      `nxos:"id"`
+	Mtu     int         `nxos:"mtu,omitempty"`
+	Speed   *uint32     `nxos:"speed"`
+	Layer3  bool        `nxos:"layer,bool=Layer3/Layer2"`
+	AdminSt testAdminSt `nxos:"adminSt,omitempty"`
+	ModTs   time.Time   `nxos:"modTs,omitempty"`
+	OperSt  string      `nxos:"operSt,readonly"`
+	Ignored string
+}
+
+type testIntfEntity struct {
+	_    struct{}     `nxos:"class=interfaceEntity"`
+	Phys []testPhysIf `nxos:",children"`
+}
+
+type testLoopback struct {
+	_  struct{} `nxos:"class=l1Loopback"`
+	Id string   `nxos:"id"`
+}
+
+type testSystem struct {
+	_    struct{}        `nxos:"class=topSystem"`
+	Intf *testIntfEntity `nxos:",children"`
+	Lo   testLoopback    `nxos:",children"`
+}
+
+// TestMarshal tests the Marshal function.
+func TestMarshal(t *testing.T) {
+	speed := uint32(100000)
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	body, err := Marshal(&testIntfEntity{Phys: []testPhysIf{
+		{Id: "eth1/1", Mtu: 9216, Speed: &speed, Layer3: true, AdminSt: 1, ModTs: ts, OperSt: "up", Ignored: "x"},
+		{Id: "eth1/2"},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "{}", body.Res().Get("interfaceEntity.attributes").Raw)
+	phys := body.Res().Get("interfaceEntity.children.#.l1PhysIf.attributes").Array()
+	assert.Len(t, phys, 2)
+	assert.Equal(t, `{"id":"eth1/1","mtu":"9216","speed":"100000","layer":"Layer3","adminSt":"up","modTs":"2024-05-01T12:30:00.000+00:00"}`, phys[0].Raw)
+	assert.Equal(t, `{"id":"eth1/2","layer":"Layer2"}`, phys[1].Raw)
+
+	_, err = Marshal(testPhysIf{AdminSt: 3})
+	assert.Error(t, err)
+	_, err = Marshal(struct{ Id string }{})
+	assert.Error(t, err)
+	_, err = Marshal("eth1/1")
+	assert.Error(t, err)
+}
+
+// TestUnmarshal tests the Unmarshal function.
+func TestUnmarshal(t *testing.T) {
+	res := gjson.Parse(`{"interfaceEntity":{"attributes":{"dn":"sys/intf"},"children":[
+		{"l1PhysIf":{"attributes":{"id":"eth1/1","mtu":"9216","speed":"100000","layer":"Layer3","adminSt":"up","modTs":"2024-05-01T12:30:00.000+00:00","operSt":"up"}}},
+		{"l1PhysIf":{"attributes":{"id":"eth1/2","layer":"Layer2","adminSt":"down"}}},
+		{"l1Loopback":{"attributes":{"id":"lo0"}}}
+	]}}`)
+	var ent testIntfEntity
+	assert.NoError(t, Unmarshal(res, &ent))
+	assert.Len(t, ent.Phys, 2)
+	p := ent.Phys[0]
+	assert.Equal(t, "eth1/1", p.Id)
+	assert.Equal(t, 9216, p.Mtu)
+	assert.Equal(t, uint32(100000), *p.Speed)
+	assert.True(t, p.Layer3)
+	assert.Equal(t, testAdminSt(1), p.AdminSt)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), p.ModTs.UTC())
+	assert.Equal(t, "up", p.OperSt)
+	assert.Nil(t, ent.Phys[1].Speed)
+	assert.False(t, ent.Phys[1].Layer3)
+
+	// Children of a reused value are replaced
+	assert.NoError(t, Unmarshal(res, &ent))
+	assert.Len(t, ent.Phys, 2)
+	assert.Equal(t, "eth1/2", ent.Phys[1].Id)
+
+	// Pointer and struct children of a reused value are replaced
+	var sys testSystem
+	assert.NoError(t, Unmarshal(gjson.Parse(`{"topSystem":{"children":[
+		{"interfaceEntity":{"attributes":{}}},
+		{"l1Loopback":{"attributes":{"id":"lo0"}}}
+	]}}`), &sys))
+	assert.NotNil(t, sys.Intf)
+	assert.Equal(t, "lo0", sys.Lo.Id)
+	assert.NoError(t, Unmarshal(gjson.Parse(`{"topSystem":{"attributes":{}}}`), &sys))
+	assert.Nil(t, sys.Intf)
+	assert.Empty(t, sys.Lo.Id)
+
+	// Class query results
+	var list []testPhysIf
+	assert.NoError(t, Unmarshal(res.Get("interfaceEntity.children"), &list))
+	assert.Len(t, list, 2)
+	var ptrs []*testPhysIf
+	assert.NoError(t, Unmarshal(res.Get("interfaceEntity.children"), &ptrs))
+	assert.Len(t, ptrs, 2)
+	assert.Equal(t, "eth1/2", ptrs[1].Id)
+
+	// Typed conversion errors
+	var intf testPhysIf
+	assert.Error(t, Unmarshal(gjson.Parse(`{"l1PhysIf":{"attributes":{"mtu":"abc"}}}`), &intf))
+	assert.Error(t, Unmarshal(gjson.Parse(`{"l1PhysIf":{"attributes":{"layer":"maybe"}}}`), &intf))
+	assert.Error(t, Unmarshal(gjson.Parse(`{"l1PhysIf":{"attributes":{"adminSt":"testing"}}}`), &intf))
+	assert.Error(t, Unmarshal(gjson.Parse(`{"l1Loopback":{"attributes":{}}}`), &intf))
+	assert.Error(t, Unmarshal(res, intf))
+}
+
+// TestMarshalRoundTrip tests that Unmarshal reverses Marshal.
+func TestMarshalRoundTrip(t *testing.T) {
+	speed := uint32(10)
+	in := testPhysIf{Id: "eth1/1", Mtu: 1500, Speed: &speed, Layer3: true, AdminSt: 2}
+	body, err := Marshal(in)
+	assert.NoError(t, err)
+	var out testPhysIf
+	assert.NoError(t, Unmarshal(body.Res(), &out))
+	assert.Equal(t, in, out)
+}
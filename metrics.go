@@ -0,0 +1,63 @@
+package nxos
+
+import (
+	"strings"
+	"time"
+)
+
+// API types used as metric labels.
+const (
+	APITypeMo      = "mo"
+	APITypeClass   = "class"
+	APITypeJsonRpc = "jsonrpc"
+	APITypeAuth    = "auth"
+	APITypeOther   = "other"
+)
+
+// MetricLabels identifies the requests a metric observation belongs to.
+type MetricLabels struct {
+	// Device is the URL of the device.
+	Device string
+	// Method is the HTTP method.
+	Method string
+	// API is the API type, i.e. one of APITypeMo, APITypeClass, APITypeJsonRpc, APITypeAuth and APITypeOther.
+	API string
+}
+
+// MetricsSink receives metrics about the requests sent by a client.
+// Implementations must be safe for concurrent use.
+type MetricsSink interface {
+	// ObserveRequest is called once for every HTTP attempt with the response
+	// status code (0 if no response was received), the error and the latency.
+	ObserveRequest(labels MetricLabels, statusCode int, err error, latency time.Duration)
+	// ObserveRetry is called whenever a request is retried after the given backoff delay.
+	ObserveRetry(labels MetricLabels, delay time.Duration)
+	// ObserveAuth is called for every login and token refresh, where operation is
+	// either "login" or "refresh".
+	ObserveAuth(device, operation string, err error, latency time.Duration)
+	// ObserveLimiterWait is called whenever a request waited for the concurrency
+	// limit or rate limit of the client, including waits aborted by the context.
+	ObserveLimiterWait(labels MetricLabels, wait time.Duration)
+}
+
+// Metrics sets the metrics sink of the client.
+func Metrics(sink MetricsSink) func(*Client) {
+	return func(client *Client) {
+		client.Metrics = sink
+	}
+}
+
+// apiType returns the API type of a URL path.
+func apiType(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/mo/"):
+		return APITypeMo
+	case strings.HasPrefix(path, "/api/class/"):
+		return APITypeClass
+	case path == "/ins" || strings.HasPrefix(path, "/ins."):
+		return APITypeJsonRpc
+	case strings.HasPrefix(path, "/api/aaa"):
+		return APITypeAuth
+	}
+	return APITypeOther
+}
@@ -0,0 +1,77 @@
+package nxos
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// recordingMetrics is a MetricsSink recording all observations.
+type recordingMetrics struct {
+	mu       sync.Mutex
+	requests []MetricLabels
+	statuses []int
+	retries  []time.Duration
+	auth     []string
+	waits    []time.Duration
+}
+
+func (m *recordingMetrics) ObserveRequest(labels MetricLabels, statusCode int, err error, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, labels)
+	m.statuses = append(m.statuses, statusCode)
+}
+
+func (m *recordingMetrics) ObserveRetry(labels MetricLabels, delay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries = append(m.retries, delay)
+}
+
+func (m *recordingMetrics) ObserveAuth(device, operation string, err error, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.auth = append(m.auth, operation)
+}
+
+func (m *recordingMetrics) ObserveLimiterWait(labels MetricLabels, wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.waits = append(m.waits, wait)
+}
+
+// TestMetrics tests the Metrics modifier.
+func TestMetrics(t *testing.T) {
+	defer gock.Off()
+	metrics := &recordingMetrics{}
+	client := testClient()
+	TimeSource(&fakeClock{now: time.Now()})(client)
+	Metrics(metrics)(client)
+	client.MaxRetries = 1
+	client.BackoffMinDelay = 2
+
+	gock.New(testURL).
+		Post("/api/aaaLogin.json").
+		Reply(200).
+		BodyString(Body{}.Set("imdata.0.aaaLogin.attributes.token", "token").Str)
+	assert.NoError(t, client.Login())
+
+	gock.New(testURL).Get("/api/class/l1PhysIf.json").Reply(503)
+	gock.New(testURL).Get("/api/class/l1PhysIf.json").Reply(200)
+	_, err := client.GetClass("l1PhysIf")
+	assert.NoError(t, err)
+
+	gock.New(testURL).Post("/ins").Reply(200)
+	_, err = client.JsonRpc([]string{"show version"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"login"}, metrics.auth)
+	assert.Equal(t, []int{503, 200, 200}, metrics.statuses)
+	assert.Equal(t, MetricLabels{Device: testURL, Method: "GET", API: APITypeClass}, metrics.requests[0])
+	assert.Equal(t, APITypeJsonRpc, metrics.requests[2].API)
+	assert.Equal(t, []time.Duration{2 * time.Second}, metrics.retries)
+}
@@ -0,0 +1,57 @@
+package nxos
+
+import (
+	"context"
+	"net/http"
+)
+
+// Handler executes a request and returns its result.
+type Handler func(ctx context.Context, req Req) (Res, error)
+
+// Middleware wraps a Handler, e.g. to add headers, record requests or
+// short-circuit them. A middleware wraps the complete call including retries.
+type Middleware func(next Handler) Handler
+
+// AttemptResult is the result of a single HTTP attempt.
+type AttemptResult struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Header contains the response headers.
+	Header http.Header
+	// Res is the parsed response body. XML documents are a string value.
+	Res Res
+}
+
+// AttemptHandler executes a single HTTP attempt of a request.
+// A returned error indicates a connection or transport failure.
+type AttemptHandler func(ctx context.Context, req Req) (AttemptResult, error)
+
+// AttemptMiddleware wraps an AttemptHandler. It is invoked for every HTTP attempt,
+// i.e. once per retry, and sees the raw status code of each response.
+type AttemptMiddleware func(next AttemptHandler) AttemptHandler
+
+// Middlewares appends middlewares wrapping every call to Do.
+// The first middleware is the outermost one, e.g.
+//
+//	dryRun := func(next nxos.Handler) nxos.Handler {
+//	    return func(ctx context.Context, req nxos.Req) (nxos.Res, error) {
+//	        if req.HttpReq.Method != "GET" {
+//	            return nxos.Res{}, nil
+//	        }
+//	        return next(ctx, req)
+//	    }
+//	}
+//	client, _ := nxos.NewClient("1.1.1.1", "user", "pwd", true, nxos.Middlewares(dryRun))
+func Middlewares(mws ...Middleware) func(*Client) {
+	return func(client *Client) {
+		client.Middleware = append(client.Middleware, mws...)
+	}
+}
+
+// AttemptMiddlewares appends middlewares wrapping every HTTP attempt of Do.
+// The first middleware is the outermost one.
+func AttemptMiddlewares(mws ...AttemptMiddleware) func(*Client) {
+	return func(client *Client) {
+		client.AttemptMiddleware = append(client.AttemptMiddleware, mws...)
+	}
+}
@@ -0,0 +1,70 @@
+package nxos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// TestMiddlewares tests the Middlewares modifier.
+func TestMiddlewares(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	var order []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req Req) (Res, error) {
+				order = append(order, name)
+				req.HttpReq.Header.Set("X-Test", name)
+				return next(ctx, req)
+			}
+		}
+	}
+	Middlewares(record("a"), record("b"))(client)
+
+	gock.New(testURL).Get("/url.json").MatchHeader("X-Test", "b").Reply(200)
+	_, err := client.Get("/url")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, order)
+
+	// Short-circuit
+	dryRun := func(next Handler) Handler {
+		return func(ctx context.Context, req Req) (Res, error) {
+			if req.HttpReq.Method != "GET" {
+				return Body{}.Set("dryRun", "true").Res(), nil
+			}
+			return next(ctx, req)
+		}
+	}
+	Middlewares(dryRun)(client)
+	res, err := client.Post("sys/bgp", "{}")
+	assert.NoError(t, err)
+	assert.Equal(t, "true", res.Get("dryRun").Str)
+}
+
+// TestAttemptMiddlewares tests the AttemptMiddlewares modifier.
+func TestAttemptMiddlewares(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	TimeSource(&fakeClock{now: time.Now()})(client)
+	client.MaxRetries = 2
+
+	var statuses []int
+	AttemptMiddlewares(func(next AttemptHandler) AttemptHandler {
+		return func(ctx context.Context, req Req) (AttemptResult, error) {
+			result, err := next(ctx, req)
+			statuses = append(statuses, result.StatusCode)
+			return result, err
+		}
+	})(client)
+
+	gock.New(testURL).Get("/url.json").Reply(503)
+	gock.New(testURL).Get("/url.json").Reply(200)
+	_, err := client.Get("/url")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{503, 200}, statuses)
+}
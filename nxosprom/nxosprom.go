@@ -0,0 +1,108 @@
+// Package nxosprom provides a Prometheus adapter for the nxos.MetricsSink interface.
+package nxosprom
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/netascode/go-nxos"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics is an nxos.MetricsSink exposing client metrics as Prometheus collectors.
+// Use nxosprom.New to create and register the collectors.
+type Metrics struct {
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	retries         *prometheus.CounterVec
+	backoff         *prometheus.CounterVec
+	auth            *prometheus.CounterVec
+	authFailures    *prometheus.CounterVec
+	authDuration    *prometheus.HistogramVec
+	limiterWait     *prometheus.HistogramVec
+}
+
+// New creates the Prometheus collectors and registers them with the given registerer, e.g.
+//
+//	metrics, _ := nxosprom.New(prometheus.DefaultRegisterer)
+//	client, _ := nxos.NewClient("1.1.1.1", "user", "pwd", true, nxos.Metrics(metrics))
+//
+// All metrics are labelled by device, method and API type, except for the
+// authentication metrics, which are labelled by device and operation.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	labels := []string{"device", "method", "api"}
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nxos_requests_total",
+			Help: "Number of HTTP requests sent to NX-OS devices.",
+		}, append(labels, "code")),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "nxos_request_duration_seconds",
+			Help:    "Latency of HTTP requests sent to NX-OS devices.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nxos_retries_total",
+			Help: "Number of retried HTTP requests.",
+		}, labels),
+		backoff: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nxos_backoff_seconds_total",
+			Help: "Total time spent in backoff delays before retries.",
+		}, labels),
+		auth: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nxos_auth_total",
+			Help: "Number of logins and token refreshes.",
+		}, []string{"device", "operation"}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nxos_auth_failures_total",
+			Help: "Number of failed logins and token refreshes.",
+		}, []string{"device", "operation"}),
+		authDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "nxos_auth_duration_seconds",
+			Help:    "Latency of logins and token refreshes.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"device", "operation"}),
+		limiterWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "nxos_limiter_wait_seconds",
+			Help:    "Time requests waited for the concurrency limit or rate limit of the client.",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+	}
+	for _, c := range []prometheus.Collector{m.requests, m.requestDuration, m.retries, m.backoff, m.auth, m.authFailures, m.authDuration, m.limiterWait} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// ObserveRequest implements nxos.MetricsSink.
+// Requests without a response are counted with the code label "error".
+func (m *Metrics) ObserveRequest(labels nxos.MetricLabels, statusCode int, err error, latency time.Duration) {
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(statusCode)
+	}
+	m.requests.WithLabelValues(labels.Device, labels.Method, labels.API, code).Inc()
+	m.requestDuration.WithLabelValues(labels.Device, labels.Method, labels.API).Observe(latency.Seconds())
+}
+
+// ObserveRetry implements nxos.MetricsSink.
+func (m *Metrics) ObserveRetry(labels nxos.MetricLabels, delay time.Duration) {
+	m.retries.WithLabelValues(labels.Device, labels.Method, labels.API).Inc()
+	m.backoff.WithLabelValues(labels.Device, labels.Method, labels.API).Add(delay.Seconds())
+}
+
+// ObserveAuth implements nxos.MetricsSink.
+func (m *Metrics) ObserveAuth(device, operation string, err error, latency time.Duration) {
+	m.auth.WithLabelValues(device, operation).Inc()
+	m.authDuration.WithLabelValues(device, operation).Observe(latency.Seconds())
+	if err != nil {
+		m.authFailures.WithLabelValues(device, operation).Inc()
+	}
+}
+
+// ObserveLimiterWait implements nxos.MetricsSink.
+func (m *Metrics) ObserveLimiterWait(labels nxos.MetricLabels, wait time.Duration) {
+	m.limiterWait.WithLabelValues(labels.Device, labels.Method, labels.API).Observe(wait.Seconds())
+}
@@ -0,0 +1,49 @@
+package nxosprom
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/netascode/go-nxos"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetrics tests the Metrics adapter using a local scrape.
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := New(reg)
+	assert.NoError(t, err)
+
+	labels := nxos.MetricLabels{Device: "https://10.0.0.1", Method: "GET", API: nxos.APITypeMo}
+	m.ObserveRequest(labels, 200, nil, 100*time.Millisecond)
+	m.ObserveRequest(labels, 0, errors.New("fail"), time.Second)
+	m.ObserveRetry(labels, 4*time.Second)
+	m.ObserveAuth("https://10.0.0.1", "login", errors.New("fail"), time.Second)
+	m.ObserveLimiterWait(labels, 500*time.Millisecond)
+
+	srv := httptest.NewServer(promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	defer srv.Close()
+	res, err := http.Get(srv.URL)
+	assert.NoError(t, err)
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+
+	assert.Contains(t, string(body), `nxos_requests_total{api="mo",code="200",device="https://10.0.0.1",method="GET"} 1`)
+	assert.Contains(t, string(body), `nxos_requests_total{api="mo",code="error",device="https://10.0.0.1",method="GET"} 1`)
+	assert.Contains(t, string(body), `nxos_request_duration_seconds_count{api="mo",device="https://10.0.0.1",method="GET"} 2`)
+	assert.Contains(t, string(body), `nxos_retries_total{api="mo",device="https://10.0.0.1",method="GET"} 1`)
+	assert.Contains(t, string(body), `nxos_backoff_seconds_total{api="mo",device="https://10.0.0.1",method="GET"} 4`)
+	assert.Contains(t, string(body), `nxos_auth_failures_total{device="https://10.0.0.1",operation="login"} 1`)
+	assert.Contains(t, string(body), `nxos_auth_duration_seconds_sum{device="https://10.0.0.1",operation="login"} 1`)
+	assert.Contains(t, string(body), `nxos_limiter_wait_seconds_sum{api="mo",device="https://10.0.0.1",method="GET"} 0.5`)
+
+	// Registering twice fails
+	_, err = New(reg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,186 @@
+package nxostest
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// filter is a parsed query-target-filter expression.
+type filter struct {
+	op   string
+	args []filterArg
+}
+
+// filterArg is either a nested expression, a property reference or a quoted value.
+type filterArg struct {
+	expr  *filter
+	value string
+}
+
+// parseFilter parses a query-target-filter expression, e.g.
+// and(eq(l1PhysIf.id,"eth1/1"),wcard(l1PhysIf.descr,"uplink")).
+func parseFilter(s string) (*filter, error) {
+	p := &filterParser{s: s}
+	f, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.s[p.pos:], p.pos)
+	}
+	return f, nil
+}
+
+type filterParser struct {
+	s   string
+	pos int
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *filterParser) expr() (*filter, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != '(' {
+		p.pos++
+	}
+	if p.pos == len(p.s) {
+		return nil, fmt.Errorf("expected '(' after %q", p.s[start:])
+	}
+	f := &filter{op: strings.TrimSpace(p.s[start:p.pos])}
+	p.pos++
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("unterminated expression %s", f.op)
+		}
+		switch p.s[p.pos] {
+		case ')':
+			p.pos++
+			return f, nil
+		case ',':
+			p.pos++
+			continue
+		case '"':
+			end := strings.IndexByte(p.s[p.pos+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", p.pos)
+			}
+			f.args = append(f.args, filterArg{value: p.s[p.pos+1 : p.pos+1+end]})
+			p.pos += end + 2
+		default:
+			end := strings.IndexAny(p.s[p.pos:], ",()")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated expression %s", f.op)
+			}
+			if p.s[p.pos+end] == '(' {
+				sub, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				f.args = append(f.args, filterArg{expr: sub})
+				continue
+			}
+			f.args = append(f.args, filterArg{value: strings.TrimSpace(p.s[p.pos : p.pos+end])})
+			p.pos += end
+		}
+	}
+}
+
+// match evaluates the filter against an MO.
+func (f *filter) match(m *mo) (bool, error) {
+	switch f.op {
+	case "and", "or":
+		for _, arg := range f.args {
+			if arg.expr == nil {
+				return false, fmt.Errorf("%s expects expressions", f.op)
+			}
+			ok, err := arg.expr.match(m)
+			if err != nil {
+				return false, err
+			}
+			if f.op == "and" && !ok {
+				return false, nil
+			}
+			if f.op == "or" && ok {
+				return true, nil
+			}
+		}
+		return f.op == "and", nil
+	case "not":
+		if len(f.args) != 1 || f.args[0].expr == nil {
+			return false, fmt.Errorf("not expects one expression")
+		}
+		ok, err := f.args[0].expr.match(m)
+		return !ok, err
+	}
+
+	if len(f.args) < 2 {
+		return false, fmt.Errorf("%s expects a property and a value", f.op)
+	}
+	class, prop, ok := strings.Cut(f.args[0].value, ".")
+	if !ok {
+		return false, fmt.Errorf("invalid property %q", f.args[0].value)
+	}
+	if class != m.class {
+		return false, nil
+	}
+	actual := m.attrs[prop]
+	if prop == "dn" {
+		actual = m.dn
+	}
+	value := f.args[1].value
+	switch f.op {
+	case "eq":
+		return actual == value, nil
+	case "ne":
+		return actual != value, nil
+	case "lt", "gt", "le", "ge":
+		c := compare(actual, value)
+		switch f.op {
+		case "lt":
+			return c < 0, nil
+		case "gt":
+			return c > 0, nil
+		case "le":
+			return c <= 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "bw":
+		if len(f.args) != 3 {
+			return false, fmt.Errorf("bw expects a property and two values")
+		}
+		return compare(actual, value) >= 0 && compare(actual, f.args[2].value) <= 0, nil
+	case "wcard":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(actual), nil
+	}
+	return false, fmt.Errorf("unknown filter operator %s", f.op)
+}
+
+// compare compares two values numerically if possible, lexically otherwise.
+func compare(a, b string) int {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,661 @@
+// Package nxostest provides an in-memory NX-API simulator for testing code
+// built on the nxos client.
+//
+// The simulator emulates the REST endpoints used by the client (aaaLogin,
+// aaaRefresh, /api/mo and /api/class) backed by an in-memory MO tree, as well
+// as the /ins endpoint for JSON-RPC and ins_api messages, e.g.
+//
+//	srv := nxostest.NewServer()
+//	defer srv.Close()
+//	client, _ := srv.Client()
+//	client.Post("sys/intf/phys-[eth1/1]", `{"l1PhysIf":{"attributes":{"id":"eth1/1","mtu":"9216"}}}`)
+package nxostest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/netascode/go-nxos"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Default credentials accepted by the simulator.
+const (
+	DefaultUsername = "admin"
+	DefaultPassword = "admin"
+)
+
+// Server is an in-memory NX-API simulator.
+// Use nxostest.NewServer to start a simulator.
+type Server struct {
+	*httptest.Server
+	// Usr is the accepted username.
+	Usr string
+	// Pwd is the accepted password.
+	Pwd string
+	// ChunkSize is the size of output chunks in bytes in ins_api chunk mode.
+	ChunkSize int
+
+	mu       sync.Mutex
+	root     *mo
+	tokens   map[string]bool
+	commands map[string]command
+	history  []string
+	sessions map[string]string
+	// rnFormats maps classes to RN formats
+	rnFormats map[string]string
+}
+
+// command is a canned command response.
+type command struct {
+	body    string
+	text    string
+	code    int
+	message string
+}
+
+// DefaultChunkSize is the default size of output chunks in ins_api chunk mode.
+const DefaultChunkSize = 1024
+
+// NewServer starts a new TLS simulator with the default credentials and an
+// empty topSystem ("sys") object.
+func NewServer() *Server {
+	s := &Server{
+		Usr:       DefaultUsername,
+		Pwd:       DefaultPassword,
+		ChunkSize: DefaultChunkSize,
+		root:      &mo{attrs: map[string]string{}},
+		tokens:    map[string]bool{},
+		commands:  map[string]command{},
+		sessions:  map[string]string{},
+		rnFormats: map[string]string{},
+	}
+	for class, format := range defaultRnFormats {
+		s.rnFormats[class] = format
+	}
+	sys := newMo("topSystem", "sys", "")
+	s.root.children = append(s.root.children, sys)
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Client creates an nxos client connected to the simulator.
+func (s *Server) Client(mods ...func(*nxos.Client)) (*nxos.Client, error) {
+	return nxos.NewClient(s.URL, s.Usr, s.Pwd, true, mods...)
+}
+
+// RegisterClass registers the RN format of a class, e.g. "phys-[{id}]" for l1PhysIf.
+// Naming properties are given in braces. RN formats are needed to derive the DN
+// of child objects which have neither an rn nor a dn attribute. Formats of
+// common classes are registered by default.
+func (s *Server) RegisterClass(class, rnFormat string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rnFormats[class] = rnFormat
+}
+
+// Load merges an object into the tree, as if posted to the given DN.
+func (s *Server) Load(dn, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.post(dn, gjson.Parse(body), false)
+}
+
+// Get returns an object of the tree including its full subtree, wrapped in its class.
+func (s *Server) Get(dn string) (nxos.Res, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.lookup(dn)
+	if m == nil {
+		return nxos.Res{}, false
+	}
+	return gjson.Parse(m.json(-1, nil)), true
+}
+
+// Command registers the JSON body returned by a command.
+func (s *Server) Command(cmd, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.commands[cmd]
+	c.body = body
+	s.commands[cmd] = c
+}
+
+// CommandText registers the text output returned by a command, e.g. with the
+// cli_ascii or cli_show_ascii method.
+func (s *Server) CommandText(cmd, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.commands[cmd]
+	c.text = text
+	s.commands[cmd] = c
+}
+
+// CommandError registers an error returned by a command. With ins_api messages, the
+// message is returned as CLI error.
+func (s *Server) CommandError(cmd string, code int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands[cmd] = command{code: code, message: message}
+}
+
+// Commands returns all commands received so far.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.history...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	path := r.URL.Path
+	switch {
+	case path == "/api/aaaLogin.json":
+		s.handleLogin(w, gjson.ParseBytes(body))
+		return
+	case path == "/ins" || path == "/ins.json":
+		if msg := gjson.GetBytes(body, "ins_api"); msg.Exists() {
+			s.handleInsApi(w, r, msg)
+		} else {
+			s.handleJsonRpc(w, r, gjson.ParseBytes(body))
+		}
+		return
+	}
+
+	if !s.authenticated(r) {
+		writeError(w, http.StatusForbidden, "403", "Token was invalid (Error: Token timeout)")
+		return
+	}
+	switch {
+	case path == "/api/aaaRefresh.json":
+		token := s.newToken()
+		http.SetCookie(w, &http.Cookie{Name: "APIC-cookie", Value: token})
+		writeJSON(w, http.StatusOK, fmt.Sprintf(`{"totalCount":"1","imdata":[{"aaaRefresh":{"attributes":{"token":"%s"}}}]}`, token))
+	case strings.HasPrefix(path, "/api/mo/") && strings.HasSuffix(path, ".json"):
+		dn := strings.TrimSuffix(strings.TrimPrefix(path, "/api/mo/"), ".json")
+		s.handleMo(w, r, dn, gjson.ParseBytes(body))
+	case strings.HasPrefix(path, "/api/class/") && strings.HasSuffix(path, ".json") && r.Method == http.MethodGet:
+		class := strings.TrimSuffix(strings.TrimPrefix(path, "/api/class/"), ".json")
+		s.handleClass(w, r, class)
+	default:
+		writeError(w, http.StatusBadRequest, "400", "Request not supported by simulator")
+	}
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, body gjson.Result) {
+	if body.Get("aaaUser.attributes.name").Str != s.Usr || body.Get("aaaUser.attributes.pwd").Str != s.Pwd {
+		writeError(w, http.StatusUnauthorized, "401", "Username or password is incorrect")
+		return
+	}
+	token := s.newToken()
+	http.SetCookie(w, &http.Cookie{Name: "APIC-cookie", Value: token})
+	writeJSON(w, http.StatusOK, fmt.Sprintf(`{"totalCount":"1","imdata":[{"aaaLogin":{"attributes":{"token":"%s"}}}]}`, token))
+}
+
+func (s *Server) authenticated(r *http.Request) bool {
+	cookie, err := r.Cookie("APIC-cookie")
+	return err == nil && s.tokens[cookie.Value]
+}
+
+func (s *Server) newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	s.tokens[token] = true
+	return token
+}
+
+func (s *Server) handleMo(w http.ResponseWriter, r *http.Request, dn string, body gjson.Result) {
+	if r.Method != http.MethodGet {
+		if _, err := nxos.ParseDn(dn); err != nil {
+			writeError(w, http.StatusBadRequest, "400", err.Error())
+			return
+		}
+	}
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		s.handleGet(w, r, dn)
+		return
+	case http.MethodPost:
+		err = s.post(dn, body, false)
+	case http.MethodPut:
+		err = s.post(dn, body, true)
+	case http.MethodDelete:
+		s.delete(dn)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "405", "Method not allowed")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "1", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, `{"totalCount":"0","imdata":[]}`)
+}
+
+// lookup returns the MO with the given DN or nil.
+func (s *Server) lookup(dn string) *mo {
+	m := s.root
+	for _, rn := range splitDn(dn) {
+		m = m.child(rn)
+		if m == nil {
+			return nil
+		}
+	}
+	return m
+}
+
+// ensure returns the MO with the given DN, creating it and its ancestors if needed.
+func (s *Server) ensure(dn, class string) *mo {
+	m := s.root
+	rns := splitDn(dn)
+	for i, rn := range rns {
+		c := m.child(rn)
+		if c == nil {
+			cls := s.classFor(rn)
+			if i == len(rns)-1 && class != "" {
+				cls = class
+			}
+			c = newMo(cls, rn, m.dn)
+			m.children = append(m.children, c)
+		}
+		m = c
+	}
+	return m
+}
+
+// post merges (or replaces) the object body into the tree at the given DN.
+func (s *Server) post(dn string, body gjson.Result, replace bool) error {
+	if !body.IsObject() {
+		return fmt.Errorf("invalid JSON body")
+	}
+	var err error
+	body.ForEach(func(class, obj gjson.Result) bool {
+		err = s.apply(dn, class.Str, obj, replace)
+		return false
+	})
+	return err
+}
+
+// apply merges a single object with its children into the tree.
+func (s *Server) apply(dn, class string, obj gjson.Result, replace bool) error {
+	attrs := obj.Get("attributes")
+	if strings.Contains(attrs.Get("status").Str, "deleted") {
+		s.delete(dn)
+		return nil
+	}
+	existing := s.lookup(dn)
+	if existing != nil && existing.class != class && existing.class != "unknown" {
+		return fmt.Errorf("class mismatch for %s: %s != %s", dn, class, existing.class)
+	}
+	m := s.ensure(dn, class)
+	m.class = class
+	if replace {
+		m.attrs = map[string]string{}
+		m.attrKeys = nil
+		m.children = nil
+	}
+	var err error
+	attrs.ForEach(func(k, v gjson.Result) bool {
+		switch k.Str {
+		case "dn", "rn", "status", "childAction":
+		default:
+			m.setAttr(k.Str, v.String())
+		}
+		return true
+	})
+	obj.Get("children").ForEach(func(_, child gjson.Result) bool {
+		child.ForEach(func(childClass, childObj gjson.Result) bool {
+			var rn string
+			rn, err = s.rnFor(childClass.Str, childObj.Get("attributes"))
+			if err == nil {
+				err = s.apply(dn+"/"+rn, childClass.Str, childObj, replace)
+			}
+			return false
+		})
+		return err == nil
+	})
+	return err
+}
+
+// delete removes the object with the given DN and its subtree.
+func (s *Server) delete(dn string) {
+	rns := splitDn(dn)
+	if len(rns) == 0 {
+		return
+	}
+	parent := s.lookup(strings.Join(rns[:len(rns)-1], "/"))
+	if len(rns) == 1 {
+		parent = s.root
+	}
+	if parent != nil {
+		parent.removeChild(rns[len(rns)-1])
+	}
+}
+
+// options are the supported query parameters.
+type options struct {
+	target        string
+	targetClasses []string
+	filter        *filter
+	subtree       string
+	subtreeClass  []string
+	orderBy       string
+	page          int
+	pageSize      int
+}
+
+func parseOptions(r *http.Request) (options, error) {
+	q := r.URL.Query()
+	o := options{
+		target:        q.Get("query-target"),
+		targetClasses: splitList(q.Get("target-subtree-class")),
+		subtree:       q.Get("rsp-subtree"),
+		subtreeClass:  splitList(q.Get("rsp-subtree-class")),
+	}
+	if o.target == "" {
+		o.target = "self"
+	}
+	o.orderBy = q.Get("order-by")
+	if v := q.Get("page-size"); v != "" {
+		var err error
+		if o.pageSize, err = strconv.Atoi(v); err != nil || o.pageSize <= 0 {
+			return o, fmt.Errorf("invalid page-size %s", v)
+		}
+		if v := q.Get("page"); v != "" {
+			if o.page, err = strconv.Atoi(v); err != nil || o.page < 0 {
+				return o, fmt.Errorf("invalid page %s", v)
+			}
+		}
+	}
+	if o.subtree == "" {
+		o.subtree = "no"
+	}
+	switch o.target {
+	case "self", "children", "subtree":
+	default:
+		return o, fmt.Errorf("invalid query-target %s", o.target)
+	}
+	switch o.subtree {
+	case "no", "children", "full":
+	default:
+		return o, fmt.Errorf("invalid rsp-subtree %s", o.subtree)
+	}
+	if f := q.Get("query-target-filter"); f != "" {
+		var err error
+		o.filter, err = parseFilter(f)
+		if err != nil {
+			return o, fmt.Errorf("invalid query-target-filter: %w", err)
+		}
+	}
+	return o, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// depth returns the rsp-subtree depth.
+func (o options) depth() int {
+	switch o.subtree {
+	case "children":
+		return 1
+	case "full":
+		return -1
+	}
+	return 0
+}
+
+// selected reports whether a target MO is included in the response.
+func (o options) selected(m *mo, self bool) (bool, error) {
+	if !self && len(o.targetClasses) > 0 {
+		found := false
+		for _, c := range o.targetClasses {
+			found = found || c == m.class
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	if o.filter == nil {
+		return true, nil
+	}
+	return o.filter.match(m)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request, dn string) {
+	o, err := parseOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "400", err.Error())
+		return
+	}
+	m := s.lookup(dn)
+	if m == nil {
+		writeJSON(w, http.StatusOK, `{"totalCount":"0","imdata":[]}`)
+		return
+	}
+	var targets []*mo
+	switch o.target {
+	case "self":
+		targets = []*mo{m}
+	case "children":
+		targets = m.children
+	case "subtree":
+		m.walk(func(d *mo) { targets = append(targets, d) })
+	}
+	s.writeResults(w, o, targets, o.target == "self")
+}
+
+func (s *Server) handleClass(w http.ResponseWriter, r *http.Request, class string) {
+	o, err := parseOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "400", err.Error())
+		return
+	}
+	var targets []*mo
+	s.root.walk(func(d *mo) {
+		if d.class == class {
+			targets = append(targets, d)
+		}
+	})
+	s.writeResults(w, o, targets, true)
+}
+
+// writeResults writes the selected targets. The target-subtree-class option is
+// ignored if self is set, i.e. for query-target=self and class queries.
+func (s *Server) writeResults(w http.ResponseWriter, o options, targets []*mo, self bool) {
+	var selected []*mo
+	for _, t := range targets {
+		ok, err := o.selected(t, self)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "400", err.Error())
+			return
+		}
+		if ok {
+			selected = append(selected, t)
+		}
+	}
+	if o.orderBy != "" {
+		prop, dir, _ := strings.Cut(o.orderBy, "|")
+		_, prop, _ = strings.Cut(prop, ".")
+		value := func(m *mo) string {
+			if prop == "dn" {
+				return m.dn
+			}
+			return m.attrs[prop]
+		}
+		sort.SliceStable(selected, func(i, j int) bool {
+			c := compare(value(selected[i]), value(selected[j]))
+			if dir == "desc" {
+				return c > 0
+			}
+			return c < 0
+		})
+	}
+	total := len(selected)
+	if o.pageSize > 0 {
+		start := min(o.page*o.pageSize, total)
+		end := min(start+o.pageSize, total)
+		selected = selected[start:end]
+	}
+
+	res := `{"totalCount":"0","imdata":[]}`
+	for _, t := range selected {
+		res, _ = sjson.SetRaw(res, "imdata.-1", t.json(o.depth(), o.subtreeClass))
+	}
+	res, _ = sjson.Set(res, "totalCount", strconv.Itoa(total))
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleJsonRpc(w http.ResponseWriter, r *http.Request, body gjson.Result) {
+	usr, pwd, ok := r.BasicAuth()
+	if !ok || usr != s.Usr || pwd != s.Pwd {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	requests := body.Array()
+	if !body.IsArray() {
+		requests = []gjson.Result{body}
+	}
+	res := "[]"
+	for _, req := range requests {
+		cmd := req.Get("params.cmd").Str
+		s.history = append(s.history, cmd)
+		entry := `{"jsonrpc":"2.0"}`
+		entry, _ = sjson.SetRaw(entry, "id", req.Get("id").Raw)
+		c, ok := s.commands[cmd]
+		switch {
+		case ok && c.message != "":
+			entry, _ = sjson.Set(entry, "error.code", c.code)
+			entry, _ = sjson.Set(entry, "error.message", c.message)
+		case ok && req.Get("method").Str == "cli_ascii":
+			entry, _ = sjson.Set(entry, "result.msg", c.text)
+		case ok && c.body != "":
+			entry, _ = sjson.SetRaw(entry, "result.body", c.body)
+		default:
+			entry, _ = sjson.SetRaw(entry, "result", "null")
+		}
+		res, _ = sjson.SetRaw(res, "-1", entry)
+		// Remaining commands are only run with continue-on-error
+		if ok && c.message != "" && req.Get("rollback").Str != "continue-on-error" {
+			break
+		}
+	}
+	if len(requests) == 1 {
+		res = gjson.Get(res, "0").Raw
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleInsApi handles ins_api JSON messages. In chunk mode, the output of a single
+// command is returned in chunks of ChunkSize bytes, continued with the returned sid.
+// As with JSON-RPC, commands which are not registered succeed with empty output.
+func (s *Server) handleInsApi(w http.ResponseWriter, r *http.Request, msg gjson.Result) {
+	usr, pwd, ok := r.BasicAuth()
+	if !ok || usr != s.Usr || pwd != s.Pwd {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	typ := msg.Get("type").Str
+	chunked := msg.Get("chunk").Str == "1"
+	res := `{"ins_api":{"version":"1.0","sid":"eoc"}}`
+	res, _ = sjson.Set(res, "ins_api.type", typ)
+	if sid := msg.Get("sid").Str; chunked {
+		if _, ok := s.sessions[sid]; ok {
+			writeJSON(w, http.StatusOK, s.chunk(res, sid, msg.Get("input").Str))
+			return
+		}
+	}
+	var outputs []string
+	for _, cmd := range strings.Split(msg.Get("input").Str, ";") {
+		cmd = strings.TrimSpace(cmd)
+		s.history = append(s.history, cmd)
+		out := `{"msg":"Success","code":"200"}`
+		out, _ = sjson.Set(out, "input", cmd)
+		c, ok := s.commands[cmd]
+		switch {
+		case ok && c.message != "":
+			out, _ = sjson.Set(out, "code", "400")
+			out, _ = sjson.Set(out, "msg", "Input CLI command error")
+			out, _ = sjson.Set(out, "clierror", c.message)
+		case chunked:
+			sid := s.newSession()
+			s.sessions[sid] = c.text
+			if typ == "cli_show" {
+				s.sessions[sid] = c.body
+			}
+			writeJSON(w, http.StatusOK, s.chunk(res, sid, cmd))
+			return
+		case typ == "cli_show" && c.body != "":
+			out, _ = sjson.SetRaw(out, "body", c.body)
+		case typ == "cli_show_ascii" || typ == "bash":
+			out, _ = sjson.Set(out, "body", c.text)
+		}
+		outputs = append(outputs, out)
+	}
+	if len(outputs) == 1 {
+		res, _ = sjson.SetRaw(res, "ins_api.outputs.output", outputs[0])
+	} else {
+		res, _ = sjson.SetRaw(res, "ins_api.outputs.output", "["+strings.Join(outputs, ",")+"]")
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// chunk returns the next chunk of a session's output as an ins_api response.
+// Each chunk but the last gets a new sid for the rest of the output, the sid
+// is "eoc" with the last chunk.
+func (s *Server) chunk(res, sid, cmd string) string {
+	size := s.ChunkSize
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	body := s.sessions[sid]
+	delete(s.sessions, sid)
+	if len(body) > size {
+		next := s.newSession()
+		s.sessions[next] = body[size:]
+		body = body[:size]
+		res, _ = sjson.Set(res, "ins_api.sid", next)
+	}
+	out := `{"msg":"Success","code":"200"}`
+	out, _ = sjson.Set(out, "input", cmd)
+	out, _ = sjson.Set(out, "body", body)
+	res, _ = sjson.SetRaw(res, "ins_api.outputs.output", out)
+	return res
+}
+
+// newSession returns a new random session id.
+func (s *Server) newSession() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	io.WriteString(w, body)
+}
+
+func writeError(w http.ResponseWriter, status int, code, text string) {
+	body := `{"totalCount":"1","imdata":[]}`
+	body, _ = sjson.Set(body, "imdata.0.error.attributes.code", code)
+	body, _ = sjson.Set(body, "imdata.0.error.attributes.text", text)
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,335 @@
+package nxostest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netascode/go-nxos"
+	"github.com/stretchr/testify/assert"
+)
+
+func testClient(t *testing.T) (*Server, *nxos.Client) {
+	srv := NewServer()
+	t.Cleanup(srv.Close)
+	client, err := srv.Client(nxos.MaxRetries(0))
+	assert.NoError(t, err)
+	return srv, client
+}
+
+// TestLogin tests the aaaLogin and aaaRefresh endpoints.
+func TestLogin(t *testing.T) {
+	srv, client := testClient(t)
+	assert.NoError(t, client.Login())
+	assert.NotEmpty(t, client.Token)
+	assert.NoError(t, client.Refresh())
+
+	bad, _ := nxos.NewClient(srv.URL, "admin", "wrong", true)
+	assert.Error(t, bad.Login())
+}
+
+// TestPostMerge tests the merge semantics of POST requests.
+func TestPostMerge(t *testing.T) {
+	_, client := testClient(t)
+
+	_, err := client.Post("sys/intf/phys-[eth1/1]", `{"l1PhysIf":{"attributes":{"id":"eth1/1","mtu":"9216"}}}`)
+	assert.NoError(t, err)
+	_, err = client.Post("sys/intf/phys-[eth1/1]", `{"l1PhysIf":{"attributes":{"descr":"uplink"}}}`)
+	assert.NoError(t, err)
+
+	res, err := client.GetDn("sys/intf/phys-[eth1/1]")
+	assert.NoError(t, err)
+	assert.Equal(t, "9216", res.Get("l1PhysIf.attributes.mtu").Str)
+	assert.Equal(t, "uplink", res.Get("l1PhysIf.attributes.descr").Str)
+	assert.Equal(t, "sys/intf/phys-[eth1/1]", res.Get("l1PhysIf.attributes.dn").Str)
+
+	// Implicitly created ancestors
+	res, err = client.GetDn("sys/intf")
+	assert.NoError(t, err)
+	assert.True(t, res.Get("interfaceEntity").Exists())
+
+	// Class mismatch
+	_, err = client.Post("sys/intf/phys-[eth1/1]", `{"bgpEntity":{"attributes":{}}}`)
+	assert.Error(t, err)
+}
+
+// TestPostChildren tests POST requests with children and status deleted.
+func TestPostChildren(t *testing.T) {
+	_, client := testClient(t)
+
+	body := nxos.Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.id", "eth1/1").
+		Set("interfaceEntity.children.1.l1PhysIf.attributes.id", "eth1/2").
+		Set("interfaceEntity.children.2.l3LbRtdIf.attributes.id", "lo1").
+		Str
+	_, err := client.Post("sys/intf", body)
+	assert.NoError(t, err)
+
+	res, err := client.GetClass("l1PhysIf")
+	assert.NoError(t, err)
+	assert.Len(t, res.Array(), 2)
+
+	body = nxos.Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.id", "eth1/2").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.status", "deleted").
+		Str
+	_, err = client.Post("sys/intf", body)
+	assert.NoError(t, err)
+	res, _ = client.GetClass("l1PhysIf")
+	assert.Len(t, res.Array(), 1)
+
+	// Bracketed naming values
+	_, err = client.Post("sys/userext", `{"aaaUserEp":{"children":[{"aaaUser":{"attributes":{"name":"admin2"}}}]}}`)
+	assert.NoError(t, err)
+	res, _ = client.GetDn("sys/userext/user-[admin2]")
+	assert.Equal(t, "sys/userext/user-[admin2]", res.Get("aaaUser.attributes.dn").Str)
+
+	// Unknown class without rn
+	_, err = client.Post("sys/intf", `{"interfaceEntity":{"children":[{"fooBar":{"attributes":{"name":"a"}}}]}}`)
+	assert.Error(t, err)
+}
+
+// TestPut tests the replace semantics of PUT requests.
+func TestPut(t *testing.T) {
+	srv, client := testClient(t)
+	assert.NoError(t, srv.Load("sys/intf", `{"interfaceEntity":{"children":[{"l1PhysIf":{"attributes":{"id":"eth1/1","descr":"a"}}}]}}`))
+
+	_, err := client.Put("sys/intf", `{"interfaceEntity":{"children":[{"l1PhysIf":{"attributes":{"id":"eth1/2"}}}]}}`)
+	assert.NoError(t, err)
+	res, _ := srv.Get("sys/intf")
+	assert.Len(t, res.Get("interfaceEntity.children").Array(), 1)
+	assert.Equal(t, "eth1/2", res.Get("interfaceEntity.children.0.l1PhysIf.attributes.id").Str)
+}
+
+// TestDelete tests DELETE requests.
+func TestDelete(t *testing.T) {
+	srv, client := testClient(t)
+	assert.NoError(t, srv.Load("sys/bgp/inst", `{"bgpInst":{"attributes":{"asn":"65000"}}}`))
+
+	_, err := client.DeleteDn("sys/bgp")
+	assert.NoError(t, err)
+	_, ok := srv.Get("sys/bgp/inst")
+	assert.False(t, ok)
+	res, err := client.GetDn("sys/bgp")
+	assert.NoError(t, err)
+	assert.False(t, res.Exists())
+
+	// Empty or invalid DN
+	_, err = client.DeleteDn("")
+	assert.ErrorIs(t, err, nxos.ErrInvalidArgument)
+	_, err = client.DeleteDn("sys/bgp]")
+	assert.ErrorIs(t, err, nxos.ErrInvalidArgument)
+	_, ok = srv.Get("sys")
+	assert.True(t, ok)
+}
+
+// TestQuery tests query-target, rsp-subtree and query-target-filter handling.
+func TestQuery(t *testing.T) {
+	srv, client := testClient(t)
+	assert.NoError(t, srv.Load("sys/intf", nxos.Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.id", "eth1/1").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.mtu", "1500").
+		Set("interfaceEntity.children.1.l1PhysIf.attributes.id", "eth1/2").
+		Set("interfaceEntity.children.1.l1PhysIf.attributes.mtu", "9216").
+		Set("interfaceEntity.children.2.l3LbRtdIf.attributes.id", "lo1").
+		Str))
+
+	res, err := client.Get("/api/mo/sys/intf", nxos.Query("query-target", "children"))
+	assert.NoError(t, err)
+	assert.Equal(t, "3", res.Get("totalCount").Str)
+
+	res, _ = client.Get("/api/mo/sys", nxos.Query("query-target", "subtree"), nxos.Query("target-subtree-class", "l1PhysIf"))
+	assert.Len(t, res.Get("imdata").Array(), 2)
+
+	res, _ = client.GetDn("sys/intf", nxos.Query("rsp-subtree", "children"))
+	assert.Len(t, res.Get("interfaceEntity.children").Array(), 3)
+
+	res, _ = client.GetDn("sys", nxos.Query("rsp-subtree", "full"), nxos.Query("rsp-subtree-class", "l3LbRtdIf"))
+	assert.Len(t, res.Get("topSystem.children.0.interfaceEntity.children").Array(), 1)
+
+	res, _ = client.GetClass("l1PhysIf", nxos.Query("query-target-filter", `eq(l1PhysIf.id,"eth1/2")`))
+	assert.Len(t, res.Array(), 1)
+	assert.Equal(t, "9216", res.Get("0.l1PhysIf.attributes.mtu").Str)
+
+	res, _ = client.GetClass("l1PhysIf", nxos.Query("query-target-filter", `or(gt(l1PhysIf.mtu,"2000"),wcard(l1PhysIf.id,"1/1$"))`))
+	assert.Len(t, res.Array(), 2)
+
+	res, _ = client.GetClass("l1PhysIf", nxos.Query("query-target-filter", `and(bw(l1PhysIf.mtu,"1000","2000"),not(eq(l1PhysIf.id,"eth1/2")))`))
+	assert.Len(t, res.Array(), 1)
+
+	_, err = client.GetClass("l1PhysIf", nxos.Query("query-target-filter", `eq(l1PhysIf.id`))
+	assert.Error(t, err)
+}
+
+// TestJsonRpc tests the JSON-RPC endpoint.
+func TestJsonRpc(t *testing.T) {
+	srv, client := testClient(t)
+	srv.Command("show version", `{"nxos_ver_str":"10.3(2)"}`)
+	srv.CommandError("show foo", -32602, "Invalid params")
+
+	res, err := client.JsonRpc([]string{"show version"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.3(2)", res.Get("result.body.nxos_ver_str").Str)
+
+	res, err = client.JsonRpc([]string{"conf t", "show foo", "show version"})
+	var rpcErr *nxos.JsonRpcError
+	assert.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "show foo", rpcErr.Command)
+	assert.Equal(t, "Invalid params", res.Get("1.error.message").Str)
+	assert.Equal(t, []string{"show version", "conf t", "show foo"}, srv.Commands())
+
+	results, err := client.JsonRpcResults([]string{"show foo", "show version"}, nxos.Rollback(nxos.ContinueOnError))
+	assert.Error(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, -32602, results[0].Error.Code)
+	assert.Equal(t, "10.3(2)", results[1].Body.Get("nxos_ver_str").Str)
+}
+
+// TestInsApi tests ins_api messages and chunk mode.
+func TestInsApi(t *testing.T) {
+	srv, client := testClient(t)
+	srv.Command("show version", `{"nxos_ver_str":"10.3(2)"}`)
+	srv.CommandText("show clock", "19:37:03\n")
+	config := strings.Repeat("interface loopback1\n", 200)
+	srv.CommandText("show running-config", config)
+	srv.CommandError("show foo", 400, "% Invalid command\n")
+
+	results, err := client.Cli([]string{"show version", "show clock"}, nxos.CliUsing(nxos.MethodCliShow))
+	assert.NoError(t, err)
+	assert.Equal(t, "10.3(2)", results[0].Body.Get("nxos_ver_str").Str)
+
+	results, err = client.Cli([]string{"show clock"}, nxos.CliUsing(nxos.MethodCliShowAscii))
+	assert.NoError(t, err)
+	assert.Equal(t, "19:37:03\n", results[0].Text)
+
+	_, err = client.Cli([]string{"show foo"}, nxos.CliUsing(nxos.MethodCliShowAscii))
+	assert.ErrorIs(t, err, nxos.ErrInvalidArgument)
+
+	// Unregistered commands succeed with empty output
+	results, err = client.Cli([]string{"interface eth1/1", "description uplink"}, nxos.CliUsing(nxos.MethodCliConf))
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Empty(t, results[1].Text)
+
+	srv.ChunkSize = 1000
+	var out strings.Builder
+	var chunks int
+	n, err := client.ShowChunkedTo(&out, "show running-config", nxos.ChunkProgressFunc(func(p nxos.ChunkProgress) {
+		chunks = p.Chunks
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(config)), n)
+	assert.Equal(t, config, out.String())
+	assert.Equal(t, 4, chunks)
+
+	result, err := client.ShowChunked("show version", nxos.ChunkMethod(nxos.MethodCliShow))
+	assert.NoError(t, err)
+	assert.Equal(t, "10.3(2)", result.Body.Get("nxos_ver_str").Str)
+}
+
+// TestPagination tests page, page-size and order-by handling.
+func TestPagination(t *testing.T) {
+	srv, client := testClient(t)
+	body := nxos.Body{}
+	for i, id := range []string{"eth1/3", "eth1/1", "eth1/5", "eth1/2", "eth1/4"} {
+		body = body.Set(fmt.Sprintf("interfaceEntity.children.%d.l1PhysIf.attributes.id", i), id)
+	}
+	assert.NoError(t, srv.Load("sys/intf", body.Str))
+
+	res, err := client.GetClassAll("l1PhysIf", 2)
+	assert.NoError(t, err)
+	var ids []string
+	for _, obj := range res.Array() {
+		ids = append(ids, obj.Get("l1PhysIf.attributes.id").Str)
+	}
+	assert.Equal(t, []string{"eth1/1", "eth1/2", "eth1/3", "eth1/4", "eth1/5"}, ids)
+
+	res, _ = client.Get("/api/class/l1PhysIf", nxos.Query("page", "2"), nxos.Query("page-size", "2"))
+	assert.Equal(t, "5", res.Get("totalCount").Str)
+	assert.Len(t, res.Get("imdata").Array(), 1)
+}
+
+// TestApply tests that Client.Apply converges against the simulator.
+func TestApply(t *testing.T) {
+	_, client := testClient(t)
+	desired := nxos.Body{}.
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.id", "eth1/1").
+		Set("interfaceEntity.children.0.l1PhysIf.attributes.mtu", "9216")
+
+	res, err := client.Apply("sys/intf", desired)
+	assert.NoError(t, err)
+	assert.True(t, res.Changed())
+
+	res, err = client.Apply("sys/intf", desired)
+	assert.NoError(t, err)
+	assert.False(t, res.Changed())
+
+	_, err = client.Post("sys/intf/phys-[eth1/2]", `{"l1PhysIf":{"attributes":{"id":"eth1/2"}}}`)
+	assert.NoError(t, err)
+	res, err = client.Apply("sys/intf", desired, nxos.DeleteUnmanaged())
+	assert.NoError(t, err)
+	assert.Len(t, res.Changes, 1)
+	obj, _ := client.GetDn("sys/intf/phys-[eth1/2]")
+	assert.False(t, obj.Exists())
+}
+
+// TestBatch tests that a Batch is applied in a single request.
+func TestBatch(t *testing.T) {
+	_, client := testClient(t)
+	_, err := client.Post("sys/intf/phys-[eth1/2]", `{"l1PhysIf":{"attributes":{"id":"eth1/2"}}}`)
+	assert.NoError(t, err)
+
+	_, err = client.NewBatch().
+		Set("sys/inst-[VRF1]", nxos.Body{}.Set("l3Inst.attributes.name", "VRF1")).
+		Set("sys/intf/phys-[eth1/1]", nxos.Body{}.Set("l1PhysIf.attributes.id", "eth1/1")).
+		Set("sys/intf/phys-[eth1/1]/rtvrfMbr", nxos.Body{}.Set("nwRtVrfMbr.attributes.tDn", "sys/inst-[VRF1]")).
+		Delete("sys/intf/phys-[eth1/2]", "l1PhysIf").
+		Submit()
+	assert.NoError(t, err)
+
+	res, _ := client.GetDn("sys/intf/phys-[eth1/1]/rtvrfMbr")
+	assert.Equal(t, "sys/inst-[VRF1]", res.Get("nwRtVrfMbr.attributes.tDn").Str)
+	res, _ = client.GetDn("sys/inst-[VRF1]")
+	assert.True(t, res.Exists())
+	res, _ = client.GetDn("sys/intf/phys-[eth1/2]")
+	assert.False(t, res.Exists())
+}
+
+// TestCommitConfirm tests that the scheduler is configured before the checkpoint is created.
+func TestCommitConfirm(t *testing.T) {
+	srv, client := testClient(t)
+	srv.CommandText("rollback running-config checkpoint cc1 atomic", "Rollback completed successfully.\n")
+
+	_, err := client.CommitConfirm(time.Minute, func() error {
+		_, err := client.JsonRpc([]string{"configure terminal", "hostname leaf1"})
+		if err != nil {
+			return err
+		}
+		return errors.New("verification failed")
+	}, nxos.CommitName("cc1"))
+	assert.ErrorContains(t, err, "verification failed")
+	assert.Equal(t, []string{
+		"configure terminal",
+		"feature scheduler",
+		"scheduler job name cc1",
+		"rollback running-config checkpoint cc1 atomic",
+		"configure terminal",
+		"no scheduler schedule name cc1",
+		"no scheduler job name cc1",
+		"exit",
+		"scheduler schedule name cc1",
+		"job name cc1",
+		"time start +2",
+		"exit",
+		"checkpoint cc1 description commit confirm",
+		"configure terminal",
+		"hostname leaf1",
+		"rollback running-config checkpoint cc1 atomic",
+		"configure terminal",
+		"no scheduler schedule name cc1",
+		"no scheduler job name cc1",
+		"no checkpoint cc1",
+	}, srv.Commands())
+}
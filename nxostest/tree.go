@@ -0,0 +1,210 @@
+package nxostest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/netascode/go-nxos"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// mo is a managed object in the simulated DME tree.
+type mo struct {
+	class    string
+	rn       string
+	dn       string
+	attrs    map[string]string
+	attrKeys []string
+	children []*mo
+}
+
+func newMo(class, rn, parentDn string) *mo {
+	dn := rn
+	if parentDn != "" {
+		dn = parentDn + "/" + rn
+	}
+	return &mo{class: class, rn: rn, dn: dn, attrs: map[string]string{}}
+}
+
+// child returns the child with the given RN.
+func (m *mo) child(rn string) *mo {
+	for _, c := range m.children {
+		if c.rn == rn {
+			return c
+		}
+	}
+	return nil
+}
+
+// removeChild removes the child with the given RN.
+func (m *mo) removeChild(rn string) {
+	for i, c := range m.children {
+		if c.rn == rn {
+			m.children = append(m.children[:i], m.children[i+1:]...)
+			return
+		}
+	}
+}
+
+// setAttr sets an attribute, preserving insertion order.
+func (m *mo) setAttr(k, v string) {
+	if _, ok := m.attrs[k]; !ok {
+		m.attrKeys = append(m.attrKeys, k)
+	}
+	m.attrs[k] = v
+}
+
+// walk calls fn for the MO and all its descendants in depth-first order.
+func (m *mo) walk(fn func(*mo)) {
+	fn(m)
+	for _, c := range m.children {
+		c.walk(fn)
+	}
+}
+
+// json renders the MO, including children up to the given depth (-1 for unlimited).
+// Only children of the given classes are included if classes is not empty.
+func (m *mo) json(depth int, classes []string) string {
+	body := "{}"
+	body, _ = sjson.Set(body, escapeKey(m.class)+".attributes.dn", m.dn)
+	for _, k := range m.attrKeys {
+		body, _ = sjson.Set(body, escapeKey(m.class)+".attributes."+escapeKey(k), m.attrs[k])
+	}
+	if depth != 0 {
+		for _, c := range m.children {
+			if len(classes) > 0 && !c.hasClass(classes) {
+				continue
+			}
+			body, _ = sjson.SetRaw(body, escapeKey(m.class)+".children.-1", c.json(depth-1, classes))
+		}
+	}
+	return body
+}
+
+// hasClass reports whether the MO or any of its descendants is of one of the classes.
+func (m *mo) hasClass(classes []string) bool {
+	found := false
+	m.walk(func(d *mo) {
+		for _, c := range classes {
+			if d.class == c {
+				found = true
+			}
+		}
+	})
+	return found
+}
+
+var keyEscaper = strings.NewReplacer(".", `\.`, "*", `\*`, "?", `\?`)
+
+func escapeKey(k string) string {
+	return keyEscaper.Replace(k)
+}
+
+// splitDn splits a DN into RNs.
+func splitDn(dn string) []string {
+	var rns []string
+	for _, rn := range nxos.Dn(dn).Rns() {
+		rns = append(rns, string(rn))
+	}
+	return rns
+}
+
+// defaultRnFormats maps classes to RN formats. Naming properties are given in braces.
+var defaultRnFormats = map[string]string{
+	"topSystem":           "sys",
+	"interfaceEntity":     "intf",
+	"l1PhysIf":            "phys-[{id}]",
+	"l3LbRtdIf":           "lb-[{id}]",
+	"sviIf":               "svi-[{id}]",
+	"pcAggrIf":            "aggr-[{id}]",
+	"bgpEntity":           "bgp",
+	"bgpInst":             "inst",
+	"bgpDom":              "dom-[{name}]",
+	"bgpPeer":             "peer-[{addr}]",
+	"bgpPeerAf":           "af-[{type}]",
+	"l3Inst":              "inst-[{name}]",
+	"l3Ctx":               "ctx-[{name}]",
+	"bdEntity":            "bd",
+	"l2BD":                "bd-[{fabEncap}]",
+	"ipv4Entity":          "ipv4",
+	"ipv4Inst":            "inst",
+	"ipv4Dom":             "dom-[{name}]",
+	"ipv4If":              "if-[{id}]",
+	"ipv4Addr":            "addr-[{addr}]",
+	"fmEntity":            "fm",
+	"fmBgp":               "bgp",
+	"fmInterfaceVlan":     "ifvlan",
+	"fmNvo":               "nvo",
+	"aaaUserEp":           "userext",
+	"aaaUser":             "user-[{name}]",
+	"nwRtVrfMbr":          "rtvrfMbr",
+	"l1RsAttEntityPCons":  "rsattEntityPCons",
+	"ethpmPhysIf":         "phys",
+	"rtctrlRtMap":         "rtmap-[{name}]",
+	"rtctrlEntity":        "rpm",
+	"rtctrlRtMapEntry":    "ent-{order}",
+	"ospfEntity":          "ospf",
+	"ospfInst":            "inst-{name}",
+	"ospfDom":             "dom-{name}",
+	"vrfEntity":           "vrf",
+	"nvoEps":              "eps",
+	"nvoEp":               "epId-[{epId}]",
+	"l2VlanMemberIf":      "vlanmbr",
+	"stpEntity":           "stp",
+	"ntpdEntity":          "time",
+	"datetimeNtpProvider": "prov-[{name}]",
+}
+
+var namingProp = regexp.MustCompile(`\{([^}]+)\}`)
+
+// rnFor derives the RN of an MO from its class and attributes.
+func (s *Server) rnFor(class string, attrs gjson.Result) (string, error) {
+	if rn := attrs.Get("rn").Str; rn != "" {
+		return rn, nil
+	}
+	if dn := attrs.Get("dn").Str; dn != "" {
+		rns := splitDn(dn)
+		return rns[len(rns)-1], nil
+	}
+	format, ok := s.rnFormats[class]
+	if !ok {
+		return "", fmt.Errorf("unknown RN format for class %s, set the rn or dn attribute or register the class", class)
+	}
+	var err error
+	rn := namingProp.ReplaceAllStringFunc(format, func(m string) string {
+		prop := m[1 : len(m)-1]
+		v := attrs.Get(escapeKey(prop))
+		if !v.Exists() {
+			err = fmt.Errorf("missing naming property %s of class %s", prop, class)
+		}
+		return v.String()
+	})
+	return rn, err
+}
+
+// classFor guesses the class of an implicitly created MO from its RN using
+// the longest matching RN format prefix.
+func (s *Server) classFor(rn string) string {
+	classes := make([]string, 0, len(s.rnFormats))
+	for class := range s.rnFormats {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	best, bestLen := "unknown", 0
+	for _, class := range classes {
+		format := s.rnFormats[class]
+		prefix := format
+		if i := strings.Index(format, "{"); i >= 0 {
+			prefix = format[:i]
+		} else if format != rn {
+			continue
+		}
+		if strings.HasPrefix(rn, prefix) && len(prefix) > bestLen {
+			best, bestLen = class, len(prefix)
+		}
+	}
+	return best
+}
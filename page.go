@@ -0,0 +1,91 @@
+package nxos
+
+import (
+	"context"
+	"iter"
+	"strconv"
+	"strings"
+)
+
+// DefaultPageSize is the page size used by GetClassIter if no page size is given.
+const DefaultPageSize int = 1000
+
+// GetClassIter returns an iterator over all objects of a class, fetched page by page
+// using the page and page-size query parameters. Results are ordered by DN unless an
+// order-by query parameter is passed. Each object is still wrapped in its class, e.g.
+//
+//	for obj, err := range client.GetClassIter("l2FmEntry", 500) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    println(obj.Get("l2FmEntry.attributes.dn").Str)
+//	}
+//
+// Iteration stops after the first error.
+func (client *Client) GetClassIter(class string, pageSize int, mods ...func(*Req)) iter.Seq2[Res, error] {
+	return client.GetClassIterCtx(context.Background(), class, pageSize, mods...)
+}
+
+// GetClassIterCtx returns an iterator over all objects of a class using the given context.
+// Iteration stops after the first error, including cancellation of the context.
+// See GetClassIter for details.
+func (client *Client) GetClassIterCtx(ctx context.Context, class string, pageSize int, mods ...func(*Req)) iter.Seq2[Res, error] {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return func(yield func(Res, error) bool) {
+		fetched := 0
+		for page := 0; ; page++ {
+			pageMods := append(append([]func(*Req){}, mods...),
+				orderBy(class+".dn|asc"),
+				Query("page", strconv.Itoa(page)),
+				Query("page-size", strconv.Itoa(pageSize)),
+			)
+			res, err := client.GetCtx(ctx, "/api/class/"+class, pageMods...)
+			if err != nil {
+				yield(Res{}, err)
+				return
+			}
+			objs := res.Get("imdata").Array()
+			for _, obj := range objs {
+				if !yield(obj, nil) {
+					return
+				}
+			}
+			fetched += len(objs)
+			total, _ := strconv.Atoi(res.Get("totalCount").Str)
+			if len(objs) < pageSize || (total > 0 && fetched >= total) {
+				return
+			}
+		}
+	}
+}
+
+// GetClassAll fetches all objects of a class page by page and returns them as a
+// single result in the same format as GetClass.
+func (client *Client) GetClassAll(class string, pageSize int, mods ...func(*Req)) (Res, error) {
+	return client.GetClassAllCtx(context.Background(), class, pageSize, mods...)
+}
+
+// GetClassAllCtx fetches all objects of a class page by page using the given context.
+// See GetClassAll for details.
+func (client *Client) GetClassAllCtx(ctx context.Context, class string, pageSize int, mods ...func(*Req)) (Res, error) {
+	var raws []string
+	for obj, err := range client.GetClassIterCtx(ctx, class, pageSize, mods...) {
+		if err != nil {
+			return Res{}, err
+		}
+		raws = append(raws, obj.Raw)
+	}
+	return Body{Str: "[" + strings.Join(raws, ",") + "]"}.Res(), nil
+}
+
+// orderBy sets the order-by query parameter unless it is already set.
+func orderBy(v string) func(*Req) {
+	return func(req *Req) {
+		if req.HttpReq.URL.Query().Has("order-by") {
+			return
+		}
+		Query("order-by", v)(req)
+	}
+}
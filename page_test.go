@@ -0,0 +1,96 @@
+package nxos
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// TestClientGetClassIter tests the Client::GetClassIter method.
+func TestClientGetClassIter(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).
+		Get("/api/class/l1PhysIf.json").
+		MatchParam("page", "0").
+		MatchParam("page-size", "2").
+		MatchParam("order-by", `l1PhysIf\.dn\|asc`).
+		Reply(200).
+		BodyString(Body{}.
+			Set("totalCount", "3").
+			Set("imdata.0.l1PhysIf.attributes.id", "eth1/1").
+			Set("imdata.1.l1PhysIf.attributes.id", "eth1/2").
+			Str)
+	gock.New(testURL).
+		Get("/api/class/l1PhysIf.json").
+		MatchParam("page", "1").
+		Reply(200).
+		BodyString(Body{}.
+			Set("totalCount", "3").
+			Set("imdata.0.l1PhysIf.attributes.id", "eth1/3").
+			Str)
+
+	var ids []string
+	for obj, err := range client.GetClassIter("l1PhysIf", 2) {
+		assert.NoError(t, err)
+		ids = append(ids, obj.Get("l1PhysIf.attributes.id").Str)
+	}
+	assert.Equal(t, []string{"eth1/1", "eth1/2", "eth1/3"}, ids)
+	assert.True(t, gock.IsDone())
+
+	// Early break does not fetch further pages
+	gock.New(testURL).
+		Get("/api/class/l1PhysIf.json").
+		MatchParam("page", "0").
+		Reply(200).
+		BodyString(Body{}.
+			Set("imdata.0.l1PhysIf.attributes.id", "eth1/1").
+			Set("imdata.1.l1PhysIf.attributes.id", "eth1/2").
+			Str)
+	for range client.GetClassIter("l1PhysIf", 2) {
+		break
+	}
+	assert.True(t, gock.IsDone())
+
+	// Error
+	gock.New(testURL).Get("/api/class/l1PhysIf.json").ReplyError(errors.New("fail"))
+	for _, err := range client.GetClassIter("l1PhysIf", 2) {
+		assert.Error(t, err)
+	}
+}
+
+// TestClientGetClassAll tests the Client::GetClassAll method.
+func TestClientGetClassAll(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+
+	gock.New(testURL).
+		Get("/api/class/l1PhysIf.json").
+		MatchParam("page", "0").
+		MatchParam("order-by", `l1PhysIf\.id\|desc`).
+		Reply(200).
+		BodyString(Body{}.
+			Set("imdata.0.l1PhysIf.attributes.id", "eth1/2").
+			Set("imdata.1.l1PhysIf.attributes.id", "eth1/1").
+			Str)
+	gock.New(testURL).
+		Get("/api/class/l1PhysIf.json").
+		MatchParam("page", "1").
+		Reply(200).
+		BodyString(`{"totalCount":"2","imdata":[]}`)
+
+	res, err := client.GetClassAll("l1PhysIf", 2, Query("order-by", "l1PhysIf.id|desc"))
+	assert.NoError(t, err)
+	assert.Len(t, res.Array(), 2)
+	assert.Equal(t, "eth1/1", res.Get("1.l1PhysIf.attributes.id").Str)
+
+	// Cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetClassAllCtx(ctx, "l1PhysIf", 2)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,141 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Expr is a query-target-filter expression.
+// Expressions are created with the comparison functions, e.g. Eq, and combined
+// with And, Or and Not.
+type Expr struct {
+	op    string
+	prop  string
+	vals  []string
+	exprs []Expr
+}
+
+// String returns the filter string, e.g. eq(l1PhysIf.id,"eth1/1").
+// Invalid expressions are rendered as far as possible; use Validate to check them.
+func (e Expr) String() string {
+	s, _ := e.render()
+	return s
+}
+
+// Validate checks the expression for invalid properties and values.
+func (e Expr) Validate() error {
+	_, err := e.render()
+	return err
+}
+
+// And combines the expression with others using a logical and.
+func (e Expr) And(exprs ...Expr) Expr {
+	return And(append([]Expr{e}, exprs...)...)
+}
+
+// Or combines the expression with others using a logical or.
+func (e Expr) Or(exprs ...Expr) Expr {
+	return Or(append([]Expr{e}, exprs...)...)
+}
+
+func (e Expr) render() (string, error) {
+	var err error
+	setErr := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	switch e.op {
+	case "":
+		return "", fmt.Errorf("empty expression")
+	case "and", "or", "not":
+		if len(e.exprs) == 0 {
+			setErr(fmt.Errorf("%s requires at least one expression", e.op))
+		}
+		parts := make([]string, len(e.exprs))
+		for i, sub := range e.exprs {
+			var subErr error
+			parts[i], subErr = sub.render()
+			if subErr != nil {
+				setErr(subErr)
+			}
+		}
+		return fmt.Sprintf("%s(%s)", e.op, strings.Join(parts, ",")), err
+	}
+	if !propertyPattern.MatchString(e.prop) {
+		setErr(fmt.Errorf("invalid property %q, expected class.property", e.prop))
+	}
+	parts := []string{e.prop}
+	for _, v := range e.vals {
+		if strings.Contains(v, `"`) {
+			setErr(fmt.Errorf("value %q contains a double quote", v))
+		}
+		parts = append(parts, `"`+v+`"`)
+	}
+	if e.op == "wcard" {
+		if _, reErr := regexp.Compile(e.vals[0]); reErr != nil {
+			setErr(fmt.Errorf("invalid wcard pattern %q: %v", e.vals[0], reErr))
+		}
+	}
+	return fmt.Sprintf("%s(%s)", e.op, strings.Join(parts, ",")), err
+}
+
+func compare(op, prop, value string) Expr {
+	return Expr{op: op, prop: prop, vals: []string{value}}
+}
+
+// Eq matches objects whose property equals the value, e.g. Eq("l1PhysIf.id", "eth1/1").
+func Eq(prop, value string) Expr {
+	return compare("eq", prop, value)
+}
+
+// Ne matches objects whose property does not equal the value.
+func Ne(prop, value string) Expr {
+	return compare("ne", prop, value)
+}
+
+// Lt matches objects whose property is less than the value.
+func Lt(prop, value string) Expr {
+	return compare("lt", prop, value)
+}
+
+// Gt matches objects whose property is greater than the value.
+func Gt(prop, value string) Expr {
+	return compare("gt", prop, value)
+}
+
+// Le matches objects whose property is less than or equal to the value.
+func Le(prop, value string) Expr {
+	return compare("le", prop, value)
+}
+
+// Ge matches objects whose property is greater than or equal to the value.
+func Ge(prop, value string) Expr {
+	return compare("ge", prop, value)
+}
+
+// Bw matches objects whose property is between the two values (inclusive).
+func Bw(prop, from, to string) Expr {
+	return Expr{op: "bw", prop: prop, vals: []string{from, to}}
+}
+
+// Wcard matches objects whose property matches the regular expression.
+func Wcard(prop, pattern string) Expr {
+	return compare("wcard", prop, pattern)
+}
+
+// And matches objects matching all expressions.
+func And(exprs ...Expr) Expr {
+	return Expr{op: "and", exprs: exprs}
+}
+
+// Or matches objects matching any of the expressions.
+func Or(exprs ...Expr) Expr {
+	return Expr{op: "or", exprs: exprs}
+}
+
+// Not matches objects not matching the expression.
+func Not(expr Expr) Expr {
+	return Expr{op: "not", exprs: []Expr{expr}}
+}
@@ -0,0 +1,205 @@
+// Package query provides typed builders for NX-API query options.
+//
+// The builders return request modifiers which can be passed to any client
+// request method, e.g.
+//
+//	client.GetClass("l1PhysIf",
+//	    query.Filter(query.Eq("l1PhysIf.adminSt", "up").And(query.Wcard("l1PhysIf.descr", "uplink"))),
+//	    query.Subtree(query.Children, query.Classes("ethpmPhysIf")),
+//	)
+//
+// Invalid options are detected before the request is sent; the request then
+// fails with an error wrapping ErrInvalidQuery.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/netascode/go-nxos"
+)
+
+// ErrInvalidQuery is wrapped by all errors caused by invalid query options.
+var ErrInvalidQuery = errors.New("invalid query")
+
+var (
+	classPattern    = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+	propertyPattern = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*\.[a-zA-Z][a-zA-Z0-9]*$`)
+)
+
+// invalid marks the request as failed.
+func invalid(req *nxos.Req, format string, args ...any) {
+	if req.Err == nil {
+		req.Err = fmt.Errorf("%w: %s", ErrInvalidQuery, fmt.Sprintf(format, args...))
+	}
+}
+
+// set sets a query parameter, failing if it has already been set.
+func set(req *nxos.Req, k, v string) {
+	q := req.HttpReq.URL.Query()
+	if q.Has(k) {
+		invalid(req, "%s already set", k)
+		return
+	}
+	nxos.Query(k, v)(req)
+}
+
+// validateClasses checks that all class names are well-formed.
+func validateClasses(classes []string) error {
+	if len(classes) == 0 {
+		return fmt.Errorf("empty class list")
+	}
+	for _, c := range classes {
+		if !classPattern.MatchString(c) {
+			return fmt.Errorf("invalid class name %q", c)
+		}
+	}
+	return nil
+}
+
+// Scope is the scope of a query-target or rsp-subtree option.
+type Scope string
+
+const (
+	// Self targets the object itself (query-target=self).
+	Self Scope = "self"
+	// Children targets the direct children (query-target=children, rsp-subtree=children).
+	Children Scope = "children"
+	// SubtreeScope targets the complete subtree (query-target=subtree).
+	SubtreeScope Scope = "subtree"
+	// None returns no subtree (rsp-subtree=no).
+	None Scope = "no"
+	// Full returns the complete subtree (rsp-subtree=full).
+	Full Scope = "full"
+)
+
+// Target sets the query-target option and optionally restricts the targets to the
+// given classes (target-subtree-class). Classes cannot be combined with Self.
+func Target(scope Scope, classes ...string) func(*nxos.Req) {
+	return func(req *nxos.Req) {
+		switch scope {
+		case Self, Children, SubtreeScope:
+		default:
+			invalid(req, "invalid query-target %q", scope)
+			return
+		}
+		if len(classes) > 0 {
+			if scope == Self {
+				invalid(req, "target-subtree-class requires query-target children or subtree")
+				return
+			}
+			if err := validateClasses(classes); err != nil {
+				invalid(req, "target-subtree-class: %v", err)
+				return
+			}
+		}
+		set(req, "query-target", string(scope))
+		if len(classes) > 0 {
+			set(req, "target-subtree-class", strings.Join(classes, ","))
+		}
+	}
+}
+
+// SubtreeOption configures the response subtree, see Subtree.
+type SubtreeOption func(*subtreeOptions)
+
+type subtreeOptions struct {
+	classes  []string
+	includes []string
+}
+
+// Classes restricts the response subtree to the given classes (rsp-subtree-class).
+func Classes(classes ...string) SubtreeOption {
+	return func(o *subtreeOptions) {
+		o.classes = append(o.classes, classes...)
+	}
+}
+
+// Include adds additional information to the response subtree (rsp-subtree-include),
+// e.g. "faults", "health", "stats", "count", "relations" or "required".
+func Include(includes ...string) SubtreeOption {
+	return func(o *subtreeOptions) {
+		o.includes = append(o.includes, includes...)
+	}
+}
+
+var validIncludes = map[string]bool{
+	"faults":         true,
+	"health":         true,
+	"stats":          true,
+	"count":          true,
+	"relations":      true,
+	"no-scoped":      true,
+	"required":       true,
+	"subtree":        true,
+	"fault-records":  true,
+	"health-records": true,
+	"audit-logs":     true,
+	"event-logs":     true,
+}
+
+// Subtree sets the rsp-subtree option. Subtree classes and includes cannot be
+// combined with None.
+func Subtree(scope Scope, opts ...SubtreeOption) func(*nxos.Req) {
+	return func(req *nxos.Req) {
+		switch scope {
+		case None, Children, Full:
+		default:
+			invalid(req, "invalid rsp-subtree %q", scope)
+			return
+		}
+		o := subtreeOptions{}
+		for _, opt := range opts {
+			opt(&o)
+		}
+		if scope == None && (len(o.classes) > 0 || len(o.includes) > 0) {
+			invalid(req, "rsp-subtree-class and rsp-subtree-include require rsp-subtree children or full")
+			return
+		}
+		if len(o.classes) > 0 {
+			if err := validateClasses(o.classes); err != nil {
+				invalid(req, "rsp-subtree-class: %v", err)
+				return
+			}
+		}
+		for _, i := range o.includes {
+			if !validIncludes[i] {
+				invalid(req, "invalid rsp-subtree-include %q", i)
+				return
+			}
+		}
+		set(req, "rsp-subtree", string(scope))
+		if len(o.classes) > 0 {
+			set(req, "rsp-subtree-class", strings.Join(o.classes, ","))
+		}
+		if len(o.includes) > 0 {
+			set(req, "rsp-subtree-include", strings.Join(o.includes, ","))
+		}
+	}
+}
+
+// PropInclude sets the rsp-prop-include option, i.e. "all", "naming-only" or "config-only".
+func PropInclude(v string) func(*nxos.Req) {
+	return func(req *nxos.Req) {
+		switch v {
+		case "all", "naming-only", "config-only":
+			set(req, "rsp-prop-include", v)
+		default:
+			invalid(req, "invalid rsp-prop-include %q", v)
+		}
+	}
+}
+
+// Filter sets the query-target-filter option.
+func Filter(expr Expr) func(*nxos.Req) {
+	return func(req *nxos.Req) {
+		s, err := expr.render()
+		if err != nil {
+			invalid(req, "query-target-filter: %v", err)
+			return
+		}
+		set(req, "query-target-filter", s)
+	}
+}
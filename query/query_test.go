@@ -0,0 +1,98 @@
+package query
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/netascode/go-nxos"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+const testURL = "https://10.0.0.1"
+
+// testReq applies modifiers to a new request.
+func testReq(mods ...func(*nxos.Req)) nxos.Req {
+	client, _ := nxos.NewClient(testURL, "usr", "pwd", true)
+	return client.NewReq("GET", "/api/class/l1PhysIf", nil, mods...)
+}
+
+// TestFilter tests the Filter function and filter expressions.
+func TestFilter(t *testing.T) {
+	expr := Eq("l1PhysIf.id", "eth1/1").And(Wcard("l1PhysIf.descr", "^uplink"), Not(Bw("l1PhysIf.mtu", "1000", "2000")))
+	assert.Equal(t, `and(eq(l1PhysIf.id,"eth1/1"),wcard(l1PhysIf.descr,"^uplink"),not(bw(l1PhysIf.mtu,"1000","2000")))`, expr.String())
+	assert.NoError(t, expr.Validate())
+
+	req := testReq(Filter(Or(Gt("l1PhysIf.mtu", "1500"), Le("l1PhysIf.speed", "10"))))
+	assert.NoError(t, req.Err)
+	assert.Equal(t, `or(gt(l1PhysIf.mtu,"1500"),le(l1PhysIf.speed,"10"))`, req.HttpReq.URL.Query().Get("query-target-filter"))
+
+	// Invalid expressions
+	assert.Error(t, Eq("id", "eth1/1").Validate())
+	assert.Error(t, Eq("l1PhysIf.descr", `a"b`).Validate())
+	assert.Error(t, Wcard("l1PhysIf.descr", "(").Validate())
+	assert.Error(t, And().Validate())
+	assert.Error(t, Expr{}.Validate())
+	req = testReq(Filter(Ne("id", "1")))
+	assert.True(t, errors.Is(req.Err, ErrInvalidQuery))
+}
+
+// TestTarget tests the Target function.
+func TestTarget(t *testing.T) {
+	req := testReq(Target(SubtreeScope, "l1PhysIf", "ethpmPhysIf"))
+	assert.NoError(t, req.Err)
+	assert.Equal(t, "subtree", req.HttpReq.URL.Query().Get("query-target"))
+	assert.Equal(t, "l1PhysIf,ethpmPhysIf", req.HttpReq.URL.Query().Get("target-subtree-class"))
+
+	assert.Error(t, testReq(Target(Self, "l1PhysIf")).Err)
+	assert.Error(t, testReq(Target(Full)).Err)
+	assert.Error(t, testReq(Target(Children, "L1PhysIf")).Err)
+	assert.Error(t, testReq(Target(Children), Target(Self)).Err)
+}
+
+// TestSubtree tests the Subtree and PropInclude functions.
+func TestSubtree(t *testing.T) {
+	req := testReq(Subtree(Full, Classes("l1PhysIf"), Include("faults", "health")), PropInclude("config-only"))
+	assert.NoError(t, req.Err)
+	q := req.HttpReq.URL.Query()
+	assert.Equal(t, "full", q.Get("rsp-subtree"))
+	assert.Equal(t, "l1PhysIf", q.Get("rsp-subtree-class"))
+	assert.Equal(t, "faults,health", q.Get("rsp-subtree-include"))
+	assert.Equal(t, "config-only", q.Get("rsp-prop-include"))
+
+	assert.Error(t, testReq(Subtree(None, Classes("l1PhysIf"))).Err)
+	assert.Error(t, testReq(Subtree(SubtreeScope)).Err)
+	assert.Error(t, testReq(Subtree(Children, Include("foo"))).Err)
+	assert.Error(t, testReq(PropInclude("some")).Err)
+}
+
+// TestInvalidQueryNotSent tests that invalid queries fail before the request is sent.
+func TestInvalidQueryNotSent(t *testing.T) {
+	defer gock.Off()
+	client, _ := nxos.NewClient(testURL, "usr", "pwd", true, nxos.MaxRetries(0))
+	client.Token = "token"
+	client.LastRefresh = time.Now()
+	gock.InterceptClient(client.HttpClient)
+	gock.New(testURL).Get("/api/class/l1PhysIf.json").Reply(200)
+
+	_, err := client.GetClass("l1PhysIf", Target(Self, "l1PhysIf"))
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+	assert.False(t, gock.IsDone())
+	gock.Off()
+
+	// No login is made before an invalid query fails
+	client, _ = nxos.NewClient(testURL, "usr", "pwd", true, nxos.MaxRetries(0))
+	gock.InterceptClient(client.HttpClient)
+	gock.New(testURL).Post("/api/aaaLogin.json").Reply(200)
+	gock.New(testURL).Get("/api/mo/sys/intf.json").Reply(200)
+	gock.New(testURL).Post("/api/mo/sys/intf.json").Reply(200)
+
+	_, err = client.GetDn("sys/intf", Filter(Ne("id", "1")))
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+	_, err = client.Post("sys/intf", "{}", PropInclude("some"))
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+	assert.Len(t, gock.Pending(), 3)
+	assert.False(t, gock.HasUnmatchedRequest())
+	assert.Empty(t, client.Token)
+}
@@ -0,0 +1,152 @@
+package nxos
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimit limits the client to rps requests per second with bursts of up to burst requests.
+// The limit applies to every HTTP attempt, including retries and JSON-RPC requests.
+// Requests waiting for the limiter are aborted if their context is done.
+// NewClient returns an error if rps is not positive.
+func RateLimit(rps float64, burst int) func(*Client) {
+	return func(client *Client) {
+		if rps <= 0 {
+			client.modErr = fmt.Errorf("invalid rate limit %v", rps)
+			return
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		client.rateLimiter = &tokenBucket{
+			rate:   rps,
+			burst:  float64(burst),
+			tokens: float64(burst),
+		}
+	}
+}
+
+// MaxConcurrentRequests limits the number of requests in flight to the device at any time.
+// Zero or a negative value means no limit.
+func MaxConcurrentRequests(x int) func(*Client) {
+	return func(client *Client) {
+		if x <= 0 {
+			client.concurrency = nil
+			return
+		}
+		client.concurrency = make(chan struct{}, x)
+	}
+}
+
+// LimiterStats contains statistics of the client rate and concurrency limiters.
+type LimiterStats struct {
+	// Waits is the number of requests which had to wait for the limiters.
+	Waits int64
+	// WaitTime is the total time requests spent waiting for the limiters.
+	WaitTime time.Duration
+	// InFlight is the number of requests currently in flight.
+	InFlight int
+}
+
+// LimiterStats returns statistics of the client rate and concurrency limiters.
+func (client *Client) LimiterStats() LimiterStats {
+	return LimiterStats{
+		Waits:    client.limiterWaits.Load(),
+		WaitTime: time.Duration(client.limiterWaitTime.Load()),
+		InFlight: len(client.concurrency),
+	}
+}
+
+// acquire waits for a concurrency slot and a rate limiter token.
+// The returned function releases the concurrency slot.
+func (client *Client) acquire(ctx context.Context, labels MetricLabels) (func(), error) {
+	clock := client.clock()
+	start := clock.Now()
+	waited := false
+	release := func() {}
+
+	if client.concurrency != nil {
+		select {
+		case client.concurrency <- struct{}{}:
+		default:
+			waited = true
+			select {
+			case client.concurrency <- struct{}{}:
+			case <-ctx.Done():
+				client.recordWait(labels, clock.Now().Sub(start))
+				return nil, ctx.Err()
+			}
+		}
+		release = func() { <-client.concurrency }
+	}
+
+	if client.rateLimiter != nil {
+		if delay := client.rateLimiter.reserve(clock.Now()); delay > 0 {
+			waited = true
+			if err := clock.Sleep(ctx, delay); err != nil {
+				client.rateLimiter.cancel()
+				release()
+				client.recordWait(labels, clock.Now().Sub(start))
+				return nil, err
+			}
+		}
+	}
+
+	if waited {
+		client.recordWait(labels, clock.Now().Sub(start))
+	}
+	return release, nil
+}
+
+func (client *Client) recordWait(labels MetricLabels, d time.Duration) {
+	client.limiterWaits.Add(1)
+	client.limiterWaitTime.Add(int64(d))
+	if client.Metrics != nil {
+		client.Metrics.ObserveLimiterWait(labels, d)
+	}
+}
+
+// tokenBucket is a token bucket rate limiter.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token and returns the time to wait until it is available.
+func (tb *tokenBucket) reserve(now time.Time) time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if !tb.last.IsZero() && now.After(tb.last) {
+		tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+		if tb.tokens > tb.burst {
+			tb.tokens = tb.burst
+		}
+	}
+	if now.After(tb.last) {
+		tb.last = now
+	}
+	tb.tokens--
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// cancel returns a reserved token which was not used.
+func (tb *tokenBucket) cancel() {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.tokens++
+}
+
+// limiterCounters is embedded in Client to track limiter statistics.
+type limiterCounters struct {
+	limiterWaits    atomic.Int64
+	limiterWaitTime atomic.Int64
+}
@@ -0,0 +1,74 @@
+package nxos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// TestRateLimit tests the RateLimit modifier.
+func TestRateLimit(t *testing.T) {
+	defer gock.Off()
+	clock := &fakeClock{now: time.Now()}
+	client := testClient()
+	metrics := &recordingMetrics{}
+	TimeSource(clock)(client)
+	RateLimit(2, 1)(client)
+	Metrics(metrics)(client)
+
+	gock.New(testURL).Get("/url.json").Times(2).Reply(200)
+	_, err := client.Get("/url")
+	assert.NoError(t, err)
+	assert.Empty(t, clock.sleeps)
+	_, err = client.Get("/url")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond}, clock.sleeps)
+
+	stats := client.LimiterStats()
+	assert.Equal(t, int64(1), stats.Waits)
+	assert.Equal(t, 500*time.Millisecond, stats.WaitTime)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond}, metrics.waits)
+}
+
+// TestMaxConcurrentRequests tests the MaxConcurrentRequests modifier.
+func TestMaxConcurrentRequests(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	MaxConcurrentRequests(1)(client)
+
+	gock.New(testURL).Get("/url.json").Reply(200)
+	_, err := client.Get("/url")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, client.LimiterStats().InFlight)
+
+	// Queued request is aborted on cancellation
+	client.concurrency <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = client.GetCtx(ctx, "/url")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, client.LimiterStats().InFlight)
+	assert.Equal(t, int64(1), client.LimiterStats().Waits)
+}
+
+// TestLimiterOptions tests limiter options with values out of range.
+func TestLimiterOptions(t *testing.T) {
+	for _, rps := range []float64{0, -1} {
+		_, err := NewClient(testURL, "usr", "pwd", true, RateLimit(rps, 1))
+		assert.ErrorContains(t, err, "invalid rate limit")
+		assert.False(t, IsInvalidArgument(err))
+	}
+
+	defer gock.Off()
+	clients := []*Client{testClient(), testClient()}
+	for i, x := range []int{0, -1} {
+		MaxConcurrentRequests(x)(clients[i])
+		assert.Nil(t, clients[i].concurrency)
+		gock.New(testURL).Get("/url.json").Reply(200)
+		_, err := clients[i].Get("/url")
+		assert.NoError(t, err)
+	}
+}
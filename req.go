@@ -53,8 +53,19 @@ type Req struct {
 	Refresh bool
 	// LogPayload indicates whether logging of payloads should be enabled.
 	LogPayload bool
+	// RetryPolicy overrides the client retry policy for this request.
+	RetryPolicy RetryPolicy
+	// Err is set by request modifiers which could not be applied, e.g. because of
+	// invalid query options. Do returns it without sending the request.
+	Err error
 	// OverrideUrl indicates a URL to use instead
 	OverrideUrl string
+	// Rollback is the error handling mode of JSON-RPC requests.
+	// Pass Rollback to set it.
+	Rollback JsonRpcRollback
+	// commandFailed reports whether a response with a server error status reports
+	// failed CLI commands rather than a failure of the device, see cliRequest.
+	commandFailed func(Res) bool
 }
 
 // NoRefresh prevents token refresh check.
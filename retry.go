@@ -0,0 +1,241 @@
+package nxos
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RetryAttempt describes a failed request attempt passed to a RetryPolicy.
+type RetryAttempt struct {
+	// Attempt is the zero-based number of the failed attempt.
+	Attempt int
+	// Method is the HTTP method of the request.
+	Method string
+	// Path is the URL path of the request.
+	Path string
+	// StatusCode is the HTTP status code, or 0 if no response was received.
+	StatusCode int
+	// Code is the NX-API error code from imdata, if any.
+	Code string
+	// Err is the connection error or the *APIError describing the failure.
+	Err error
+	// RetryAfter is the delay requested by the Retry-After response header, if any.
+	RetryAfter time.Duration
+	// Elapsed is the time spent on the request since the first attempt.
+	Elapsed time.Duration
+}
+
+// RetryPolicy decides whether a failed request attempt is retried and how long
+// to wait before the next attempt.
+type RetryPolicy interface {
+	Retry(attempt RetryAttempt) (bool, time.Duration)
+}
+
+// RetryPolicyFunc is an adapter to allow the use of ordinary functions as retry policies.
+type RetryPolicyFunc func(attempt RetryAttempt) (bool, time.Duration)
+
+// Retry calls f(attempt).
+func (f RetryPolicyFunc) Retry(attempt RetryAttempt) (bool, time.Duration) {
+	return f(attempt)
+}
+
+// ExponentialBackoff is a RetryPolicy retrying connection errors and server
+// errors (HTTP 500-504 and 405) using exponential backoff with jitter. Only requests
+// using idempotent methods (GET, HEAD, OPTIONS, PUT, DELETE) are retried, unless
+// RetryNonIdempotent is set; non-idempotent requests, e.g. POST and JSON-RPC, are
+// only retried if the connection could not be established.
+type ExponentialBackoff struct {
+	// MaxRetries is the maximum number of retries.
+	MaxRetries int
+	// MinDelay is the minimum delay between two attempts.
+	MinDelay time.Duration
+	// MaxDelay is the maximum delay between two attempts, including delays requested
+	// by Retry-After headers. Zero means no maximum.
+	MaxDelay time.Duration
+	// DelayFactor is the factor by which the delay grows with every attempt.
+	DelayFactor float64
+	// MaxElapsed is the overall retry deadline measured from the first attempt.
+	// No retry is attempted if it would start after the deadline. Zero means no deadline.
+	MaxElapsed time.Duration
+	// RetryNonIdempotent also retries non-idempotent requests, e.g. POST and JSON-RPC,
+	// which may then be applied more than once.
+	RetryNonIdempotent bool
+	// RetryCodes lists additional NX-API error codes which are retried.
+	RetryCodes []string
+}
+
+// Retry implements RetryPolicy.
+func (p ExponentialBackoff) Retry(attempt RetryAttempt) (bool, time.Duration) {
+	if attempt.Attempt >= p.MaxRetries {
+		return false, 0
+	}
+	if !p.retryable(attempt) {
+		return false, 0
+	}
+	delay := p.Delay(attempt.Attempt)
+	if attempt.RetryAfter > 0 {
+		delay = attempt.RetryAfter
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+	if p.MaxElapsed > 0 && attempt.Elapsed+delay > p.MaxElapsed {
+		return false, 0
+	}
+	return true, delay
+}
+
+// Delay returns the jittered backoff delay following the given attempt.
+func (p ExponentialBackoff) Delay(attempt int) time.Duration {
+	min := float64(p.MinDelay)
+	backoff := min * math.Pow(p.DelayFactor, float64(attempt))
+	if p.MaxDelay > 0 && backoff > float64(p.MaxDelay) {
+		backoff = float64(p.MaxDelay)
+	}
+	backoff = (rand.Float64()/2+0.5)*(backoff-min) + min
+	return time.Duration(backoff)
+}
+
+func (p ExponentialBackoff) retryable(attempt RetryAttempt) bool {
+	if attempt.StatusCode == 0 {
+		return p.RetryNonIdempotent || isIdempotent(attempt.Method) || isConnectError(attempt.Err)
+	}
+	if !p.RetryNonIdempotent && !isIdempotent(attempt.Method) {
+		return false
+	}
+	if isRetryableStatus(attempt.StatusCode) {
+		return true
+	}
+	return attempt.Code != "" && slices.Contains(p.RetryCodes, attempt.Code)
+}
+
+// isRetryableStatus reports whether the HTTP status code indicates a transient failure.
+func isRetryableStatus(code int) bool {
+	return (code >= 500 && code <= 504) || code == 405
+}
+
+// isIdempotent reports whether requests using the HTTP method may safely be repeated.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isConnectError reports whether err occurred while establishing the connection,
+// i.e. before the request could have reached the device.
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// parseRetryAfter parses a Retry-After header value given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// Clock provides the current time and sleeping for retry handling.
+// A custom clock can be injected using TimeSource, e.g. to avoid sleeping in tests.
+type Clock interface {
+	Now() time.Time
+	// Sleep waits for the given duration or until the context is done,
+	// in which case the context error is returned.
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realClock is the default Clock using the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Retry sets the retry policy of the client.
+// By default an ExponentialBackoff policy derived from MaxRetries, BackoffMinDelay,
+// BackoffMaxDelay and BackoffDelayFactor is used, which only retries idempotent requests.
+func Retry(policy RetryPolicy) func(*Client) {
+	return func(client *Client) {
+		client.RetryPolicy = policy
+	}
+}
+
+// TimeSource sets the clock used for retry timing, rate limiting and commit deadlines.
+func TimeSource(clock Clock) func(*Client) {
+	return func(client *Client) {
+		client.Clock = clock
+	}
+}
+
+// ReqRetryPolicy overrides the client retry policy for a single request, e.g.
+//
+//	policy := nxos.ExponentialBackoff{MaxRetries: 2, MinDelay: time.Second, MaxDelay: 10 * time.Second, DelayFactor: 2}
+//	client.GetDn("sys/bgp", nxos.ReqRetryPolicy(policy))
+func ReqRetryPolicy(policy RetryPolicy) func(*Req) {
+	return func(req *Req) {
+		req.RetryPolicy = policy
+	}
+}
+
+// NoRetry disables retries for a single request.
+func NoRetry(req *Req) {
+	req.RetryPolicy = RetryPolicyFunc(func(RetryAttempt) (bool, time.Duration) {
+		return false, 0
+	})
+}
+
+// retryPolicy returns the retry policy for the given request.
+func (client *Client) retryPolicy(req Req) RetryPolicy {
+	if req.RetryPolicy != nil {
+		return req.RetryPolicy
+	}
+	if client.RetryPolicy != nil {
+		return client.RetryPolicy
+	}
+	return client.defaultRetryPolicy()
+}
+
+// defaultRetryPolicy returns the ExponentialBackoff policy derived from the client settings.
+func (client *Client) defaultRetryPolicy() ExponentialBackoff {
+	return ExponentialBackoff{
+		MaxRetries:  client.MaxRetries,
+		MinDelay:    time.Duration(client.BackoffMinDelay) * time.Second,
+		MaxDelay:    time.Duration(client.BackoffMaxDelay) * time.Second,
+		DelayFactor: client.BackoffDelayFactor,
+	}
+}
+
+// clock returns the configured clock or the real clock.
+func (client *Client) clock() Clock {
+	if client.Clock == nil {
+		return realClock{}
+	}
+	return client.Clock
+}
@@ -0,0 +1,144 @@
+package nxos
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// fakeClock is a Clock which records sleeps instead of sleeping.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+// TestExponentialBackoff tests the ExponentialBackoff::Retry method.
+func TestExponentialBackoff(t *testing.T) {
+	p := ExponentialBackoff{
+		MaxRetries:  3,
+		MinDelay:    time.Second,
+		MaxDelay:    10 * time.Second,
+		DelayFactor: 2,
+	}
+
+	retry, delay := p.Retry(RetryAttempt{Attempt: 0, Method: "GET", StatusCode: 503})
+	assert.True(t, retry)
+	assert.Equal(t, time.Second, delay)
+
+	retry, _ = p.Retry(RetryAttempt{Attempt: 3, Method: "GET", StatusCode: 503})
+	assert.False(t, retry)
+
+	// Client errors and NX-API errors are not retried
+	retry, _ = p.Retry(RetryAttempt{Attempt: 0, Method: "GET", StatusCode: 400, Code: "1"})
+	assert.False(t, retry)
+	p.RetryCodes = []string{"1"}
+	retry, _ = p.Retry(RetryAttempt{Attempt: 0, Method: "GET", StatusCode: 400, Code: "1"})
+	assert.True(t, retry)
+
+	// Retry-After header
+	retry, delay = p.Retry(RetryAttempt{Attempt: 0, Method: "GET", StatusCode: 503, RetryAfter: 7 * time.Second})
+	assert.True(t, retry)
+	assert.Equal(t, 7*time.Second, delay)
+	retry, delay = p.Retry(RetryAttempt{Attempt: 0, Method: "GET", StatusCode: 503, RetryAfter: time.Hour})
+	assert.True(t, retry)
+	assert.Equal(t, 10*time.Second, delay)
+
+	// Overall deadline
+	p.MaxElapsed = 5 * time.Second
+	retry, _ = p.Retry(RetryAttempt{Attempt: 1, Method: "GET", StatusCode: 503, Elapsed: 5 * time.Second})
+	assert.False(t, retry)
+
+	// Idempotency
+	p.MaxElapsed = 0
+	retry, _ = p.Retry(RetryAttempt{Attempt: 0, Method: "POST", StatusCode: 503})
+	assert.False(t, retry)
+	retry, _ = p.Retry(RetryAttempt{Attempt: 0, Method: "POST", Err: errors.New("EOF")})
+	assert.False(t, retry)
+	retry, _ = p.Retry(RetryAttempt{Attempt: 0, Method: "POST", Err: &net.OpError{Op: "dial", Err: errors.New("refused")}})
+	assert.True(t, retry)
+	retry, _ = p.Retry(RetryAttempt{Attempt: 0, Method: "PUT", StatusCode: 503})
+	assert.True(t, retry)
+	p.RetryNonIdempotent = true
+	retry, _ = p.Retry(RetryAttempt{Attempt: 0, Method: "POST", StatusCode: 503})
+	assert.True(t, retry)
+	retry, _ = p.Retry(RetryAttempt{Attempt: 0, Method: "POST", Err: errors.New("EOF")})
+	assert.True(t, retry)
+
+	// Without maximum delay
+	p = ExponentialBackoff{MaxRetries: 1, MinDelay: time.Second, DelayFactor: 2}
+	assert.GreaterOrEqual(t, p.Delay(3), time.Second)
+}
+
+// TestParseRetryAfter tests the parseRetryAfter function.
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter("Mon, 01 Jan 2024 00:00:30 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("invalid", now))
+}
+
+// TestClientRetryPolicy tests retry handling of Client::Do.
+func TestClientRetryPolicy(t *testing.T) {
+	defer gock.Off()
+	clock := &fakeClock{now: time.Now()}
+	client := testClient()
+	TimeSource(clock)(client)
+	client.MaxRetries = 2
+
+	// Retry with Retry-After header
+	gock.New(testURL).Get("/url.json").Reply(503).SetHeader("Retry-After", "3")
+	gock.New(testURL).Get("/url.json").Reply(200)
+	_, err := client.Get("/url")
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{3 * time.Second}, clock.sleeps)
+
+	// Default policy does not retry POST
+	clock.sleeps = nil
+	gock.New(testURL).Post("/url.json").Reply(503)
+	_, err = client.Post("/url", "{}")
+	assert.Error(t, err)
+	assert.Empty(t, clock.sleeps)
+
+	// Per request policy
+	gock.New(testURL).Post("/url.json").Reply(503)
+	gock.New(testURL).Post("/url.json").Reply(200)
+	_, err = client.Post("/url", "{}", ReqRetryPolicy(ExponentialBackoff{MaxRetries: 2, MinDelay: time.Second, RetryNonIdempotent: true}))
+	assert.NoError(t, err)
+	assert.Len(t, clock.sleeps, 1)
+
+	clock.sleeps = nil
+
+	gock.New(testURL).Get("/url.json").Reply(503)
+	_, err = client.Get("/url", NoRetry)
+	assert.Error(t, err)
+	assert.Empty(t, clock.sleeps)
+
+	// Client policy
+	calls := 0
+	Retry(RetryPolicyFunc(func(a RetryAttempt) (bool, time.Duration) {
+		calls++
+		assert.Equal(t, "1", a.Code)
+		return a.Attempt < 1, time.Second
+	}))(client)
+	gock.New(testURL).Get("/url.json").Times(2).Reply(400).BodyString(Body{}.Set("imdata.0.error.attributes.code", "1").Str)
+	_, err = client.Get("/url")
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, []time.Duration{time.Second}, clock.sleeps)
+}
@@ -0,0 +1,361 @@
+package nxos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultSplitMaxCommands is the default maximum number of commands per request of JsonRpcSplit.
+const DefaultSplitMaxCommands = 100
+
+// DefaultModeCommands are prefixes of commands entering a top-level configuration mode.
+// If JsonRpcSplit splits the sub-commands of such a command, it repeats the command and
+// the enclosing sub-mode commands at the start of the next request.
+var DefaultModeCommands = []string{
+	"interface ",
+	"router ",
+	"vrf context ",
+	"vlan ",
+	"route-map ",
+	"ip access-list ",
+	"ipv6 access-list ",
+	"mac access-list ",
+	"object-group ",
+	"class-map ",
+	"policy-map ",
+	"control-plane",
+	"line ",
+	"role name ",
+	"key chain ",
+	"track ",
+	"vpc domain ",
+	"monitor session ",
+	"spanning-tree mst configuration",
+	"evpn",
+	"scheduler job name ",
+	"scheduler schedule name ",
+}
+
+// FailurePolicy is the behaviour of JsonRpcSplit if a command fails.
+type FailurePolicy string
+
+const (
+	// StopOnFailure stops at the first failed command, keeping the changes of previous commands.
+	StopOnFailure FailurePolicy = "stop"
+	// ContinueOnFailure skips failed commands and runs all remaining commands.
+	ContinueOnFailure FailurePolicy = "continue"
+	// RollbackOnFailure stops at the first failed command and rolls back to a checkpoint
+	// created before the first request, undoing the changes of all requests.
+	RollbackOnFailure FailurePolicy = "rollback"
+)
+
+// SplitResult is the result of a command of JsonRpcSplit.
+type SplitResult struct {
+	// Index is the position of the command in the commands passed to JsonRpcSplit.
+	Index int
+	// Request is the number of the request the command was sent with, starting at 0.
+	Request int
+	JsonRpcResult
+}
+
+// SplitOptions are the options of JsonRpcSplit.
+type SplitOptions struct {
+	// MaxCommands is the maximum number of commands per request, defaults to DefaultSplitMaxCommands.
+	MaxCommands int
+	// MaxBytes is the maximum payload size per request, 0 for no limit.
+	MaxBytes int
+	// ModeCommands are prefixes of commands entering a top-level configuration mode,
+	// defaults to DefaultModeCommands.
+	ModeCommands []string
+	// OnFailure is the failure policy, defaults to StopOnFailure.
+	OnFailure FailurePolicy
+	// Mods are request modifiers applied to all requests.
+	Mods []func(*Req)
+}
+
+// SplitMaxCommands sets the maximum number of commands per request.
+func SplitMaxCommands(n int) func(*SplitOptions) {
+	return func(o *SplitOptions) {
+		o.MaxCommands = n
+	}
+}
+
+// SplitMaxBytes sets the maximum payload size per request.
+func SplitMaxBytes(n int) func(*SplitOptions) {
+	return func(o *SplitOptions) {
+		o.MaxBytes = n
+	}
+}
+
+// SplitModeCommands sets the prefixes of commands entering a top-level configuration mode.
+func SplitModeCommands(prefixes ...string) func(*SplitOptions) {
+	return func(o *SplitOptions) {
+		o.ModeCommands = prefixes
+	}
+}
+
+// SplitOnFailure sets the failure policy.
+func SplitOnFailure(policy FailurePolicy) func(*SplitOptions) {
+	return func(o *SplitOptions) {
+		o.OnFailure = policy
+	}
+}
+
+// SplitReqMods sets request modifiers used for all requests of JsonRpcSplit.
+func SplitReqMods(mods ...func(*Req)) func(*SplitOptions) {
+	return func(o *SplitOptions) {
+		o.Mods = append(o.Mods, mods...)
+	}
+}
+
+// JsonRpcSplit runs a large list of commands with multiple JSON-RPC requests, which
+// are sent sequentially, e.g.
+//
+//	results, err := client.JsonRpcSplit(lines, nxos.SplitMaxCommands(200), nxos.SplitOnFailure(nxos.RollbackOnFailure))
+//
+// Commands are split into requests of at most MaxCommands commands and MaxBytes bytes.
+// Indented commands are kept together with the preceding unindented command, e.g.
+//
+//	interface eth1/1
+//	  description uplink
+//	  no shutdown
+//
+// A sequence exceeding the limits is split as well; if it starts with a command entering
+// a top-level mode (see ModeCommands), that command and the less indented commands
+// enclosing the next command, e.g. "  neighbor 10.0.0.1" and "    address-family ipv4 unicast"
+// below "router bgp 65000", are repeated at the start of the next request. If the commands
+// enter configuration mode with "configure terminal", it is repeated at the start of
+// subsequent requests. Sub-commands of a mode must be indented to be kept with it. An error
+// is returned if MaxCommands cannot hold the repeated commands and one more command.
+//
+// Blank commands are not sent. The results of all other commands are returned with their
+// original index. If a command fails, the results are returned together with the
+// *JsonRpcError of the first failed command, and the remaining commands are handled
+// according to the failure policy.
+func (client *Client) JsonRpcSplit(commands []string, opts ...func(*SplitOptions)) ([]SplitResult, error) {
+	return client.JsonRpcSplitCtx(context.Background(), commands, opts...)
+}
+
+// JsonRpcSplitCtx runs a large list of commands with multiple JSON-RPC requests using the given context.
+// See JsonRpcSplit for details.
+func (client *Client) JsonRpcSplitCtx(ctx context.Context, commands []string, opts ...func(*SplitOptions)) ([]SplitResult, error) {
+	o := SplitOptions{
+		MaxCommands:  DefaultSplitMaxCommands,
+		ModeCommands: DefaultModeCommands,
+		OnFailure:    StopOnFailure,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.MaxCommands < 1 {
+		return nil, fmt.Errorf("invalid maximum number of commands %d: %w", o.MaxCommands, ErrInvalidArgument)
+	}
+	rollback := StopOnError
+	switch o.OnFailure {
+	case StopOnFailure:
+	case ContinueOnFailure:
+		rollback = ContinueOnError
+	case RollbackOnFailure:
+		rollback = RollbackOnError
+	default:
+		return nil, fmt.Errorf("unknown failure policy %q: %w", o.OnFailure, ErrInvalidArgument)
+	}
+	requests, err := splitCommands(commands, o)
+	if err != nil {
+		return nil, err
+	}
+	var results []SplitResult
+	run := func() error {
+		var failed error
+		mods := append(append([]func(*Req){}, o.Mods...), Rollback(rollback))
+		for n, request := range requests {
+			cmds, indices := request.cmds, request.indices
+			res, err := client.jsonRpc(ctx, "cli", cmds, mods...)
+			for _, r := range jsonRpcResults(res, cmds) {
+				if r.Id < 1 || r.Id > len(indices) || indices[r.Id-1] < 0 {
+					continue
+				}
+				results = append(results, SplitResult{Index: indices[r.Id-1], Request: n, JsonRpcResult: r})
+			}
+			var rpcErr *JsonRpcError
+			if err != nil && (!errors.As(err, &rpcErr) || o.OnFailure != ContinueOnFailure) {
+				return err
+			}
+			if failed == nil {
+				failed = err
+			}
+		}
+		return failed
+	}
+	if o.OnFailure == RollbackOnFailure {
+		return results, client.WithCheckpointCtx(ctx, run, CheckpointReqMods(o.Mods...))
+	}
+	return results, run()
+}
+
+// splitRequest is a request of JsonRpcSplit. Commands inserted to restore the
+// configuration mode have an index of -1.
+type splitRequest struct {
+	cmds    []string
+	indices []int
+}
+
+// splitter packs command blocks into requests.
+type splitter struct {
+	o        SplitOptions
+	requests []splitRequest
+	current  splitRequest
+	size     int
+	// commands is the number of original commands in the current request
+	commands int
+	config   bool
+}
+
+func (s *splitter) fits(n, size int) bool {
+	return len(s.current.cmds)+n <= s.o.MaxCommands && (s.o.MaxBytes <= 0 || s.size+size <= s.o.MaxBytes)
+}
+
+// fitsNext reports whether n commands of the given size fit into the next request.
+func (s *splitter) fitsNext(n, size int) bool {
+	if s.config {
+		n, size = n+1, size+commandSize("configure terminal")
+	}
+	return n <= s.o.MaxCommands && (s.o.MaxBytes <= 0 || size <= s.o.MaxBytes)
+}
+
+func (s *splitter) add(cmd string, index int) {
+	s.current.cmds = append(s.current.cmds, cmd)
+	s.current.indices = append(s.current.indices, index)
+	s.size += commandSize(cmd)
+	if index < 0 {
+		return
+	}
+	s.commands++
+	switch cmd := strings.TrimSpace(cmd); {
+	case isConfigureTerminal(cmd):
+		s.config = true
+	case cmd == "end":
+		s.config = false
+	}
+}
+
+// flush completes the current request and starts the next one, re-entering
+// configuration mode and the given modes, outermost first.
+func (s *splitter) flush(modes []string) error {
+	if s.commands == 0 {
+		return nil
+	}
+	if s.config {
+		modes = append([]string{"configure terminal"}, modes...)
+	}
+	if len(modes)+1 > s.o.MaxCommands {
+		return fmt.Errorf("maximum number of commands %d cannot hold %d commands re-entering the configuration mode and one more command: %w",
+			s.o.MaxCommands, len(modes), ErrInvalidArgument)
+	}
+	s.requests = append(s.requests, s.current)
+	s.current, s.size, s.commands = splitRequest{}, 0, 0
+	for _, cmd := range modes {
+		s.add(cmd, -1)
+	}
+	return nil
+}
+
+// splitCommands splits commands into requests.
+func splitCommands(commands []string, o SplitOptions) ([]splitRequest, error) {
+	s := splitter{o: o}
+	for _, block := range commandBlocks(commands) {
+		size := 0
+		for _, index := range block {
+			size += commandSize(commands[index])
+		}
+		if !s.fits(len(block), size) && s.fitsNext(len(block), size) {
+			if err := s.flush(nil); err != nil {
+				return nil, err
+			}
+		}
+		if s.fits(len(block), size) {
+			for _, index := range block {
+				s.add(commands[index], index)
+			}
+			continue
+		}
+		// Split a block exceeding the limits, re-entering the enclosing modes in every request
+		mode := isModeCommand(strings.TrimSpace(commands[block[0]]), o.ModeCommands)
+		// stack holds the indices of the commands enclosing the current command
+		var stack []int
+		for _, index := range block {
+			cmd := commands[index]
+			for len(stack) > 0 && indentation(commands[stack[len(stack)-1]]) >= indentation(cmd) {
+				stack = stack[:len(stack)-1]
+			}
+			if s.commands > 0 && !s.fits(1, commandSize(cmd)) {
+				var modes []string
+				if mode {
+					for _, i := range stack {
+						modes = append(modes, commands[i])
+					}
+				}
+				if err := s.flush(modes); err != nil {
+					return nil, err
+				}
+			}
+			s.add(cmd, index)
+			stack = append(stack, index)
+		}
+	}
+	if s.commands > 0 {
+		s.requests = append(s.requests, s.current)
+	}
+	return s.requests, nil
+}
+
+// commandBlocks groups commands into sequences which should be sent with the same
+// request, i.e. a command and the indented commands following it. Blank commands
+// are dropped, so that they do not separate indented commands from their parent.
+func commandBlocks(commands []string) [][]int {
+	var blocks [][]int
+	for i, cmd := range commands {
+		if strings.TrimSpace(cmd) == "" {
+			continue
+		}
+		if len(blocks) > 0 && strings.TrimLeft(cmd, " \t") != cmd {
+			blocks[len(blocks)-1] = append(blocks[len(blocks)-1], i)
+			continue
+		}
+		blocks = append(blocks, []int{i})
+	}
+	return blocks
+}
+
+// indentation returns the number of leading spaces and tabs of a command.
+func indentation(cmd string) int {
+	return len(cmd) - len(strings.TrimLeft(cmd, " \t"))
+}
+
+// isModeCommand reports whether a command enters a top-level configuration mode.
+// Commands applying a policy in a direction, e.g. "route-map X in", are sub-commands.
+func isModeCommand(cmd string, modes []string) bool {
+	if strings.HasSuffix(cmd, " in") || strings.HasSuffix(cmd, " out") {
+		return false
+	}
+	for _, prefix := range modes {
+		if strings.HasPrefix(cmd, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isConfigureTerminal reports whether a command enters configuration mode, e.g. "conf t".
+func isConfigureTerminal(cmd string) bool {
+	fields := strings.Fields(cmd)
+	return len(fields) == 2 && len(fields[0]) >= 4 &&
+		strings.HasPrefix("configure", fields[0]) && strings.HasPrefix("terminal", fields[1])
+}
+
+// commandSize returns the approximate payload size of a command in a JSON-RPC request.
+func commandSize(cmd string) int {
+	return len(jsonRpcBody("cli", []string{cmd}, RollbackOnError))
+}
@@ -0,0 +1,185 @@
+package nxos
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+var testSplitCommands = []string{
+	"configure terminal",
+	"feature bgp",
+	"interface eth1/1",
+	"  description uplink",
+	"  no shutdown",
+	"interface eth1/2",
+	"  description downlink",
+	"router bgp 65000",
+	"  neighbor 10.0.0.1",
+	"    remote-as 65001",
+	"    address-family ipv4 unicast",
+	"      route-map RM-IN in",
+	"end",
+	"copy running-config startup-config",
+}
+
+// splitIndices returns the command indices of requests.
+func splitIndices(requests []splitRequest, err error) [][]int {
+	if err != nil {
+		return nil
+	}
+	var indices [][]int
+	for _, r := range requests {
+		indices = append(indices, r.indices)
+	}
+	return indices
+}
+
+// TestSplitCommands tests splitting commands into requests.
+func TestSplitCommands(t *testing.T) {
+	o := SplitOptions{MaxCommands: 6, ModeCommands: DefaultModeCommands}
+	assert.Equal(t, [][]int{
+		{0, 1, 2, 3, 4},
+		{-1, 5, 6},
+		{-1, 7, 8, 9, 10, 11},
+		{-1, 12, 13},
+	}, splitIndices(splitCommands(testSplitCommands, o)))
+
+	o.MaxCommands = 100
+	assert.Equal(t, [][]int{{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}}, splitIndices(splitCommands(testSplitCommands, o)))
+
+	// Unindented commands after a mode command are not kept together with it
+	commands := []string{"configure terminal", "interface lo0", "  ip address 10.0.0.1/32"}
+	for i := 0; i < 500; i++ {
+		commands = append(commands, fmt.Sprintf("ip route 10.1.%d.0/24 10.0.0.2", i))
+	}
+	requests, err := splitCommands(commands, o)
+	assert.NoError(t, err)
+	assert.Len(t, requests, 6)
+	for _, r := range requests {
+		assert.LessOrEqual(t, len(r.cmds), 100)
+		assert.Greater(t, len(r.cmds), 1)
+	}
+	assert.Equal(t, "configure terminal", requests[1].cmds[0])
+	assert.Equal(t, []int{-1, 100}, requests[1].indices[:2])
+
+	// Blocks exceeding the limit are split, re-entering the mode
+	commands = []string{"configure terminal", "interface eth1/1"}
+	for i := 0; i < 150; i++ {
+		commands = append(commands, fmt.Sprintf("  description %d", i))
+	}
+	requests, err = splitCommands(commands, o)
+	assert.NoError(t, err)
+	assert.Len(t, requests, 2)
+	assert.Len(t, requests[0].cmds, 100)
+	assert.Equal(t, []string{"configure terminal", "interface eth1/1", "  description 98"}, requests[1].cmds[:3])
+	assert.Equal(t, []int{-1, -1, 100}, requests[1].indices[:3])
+
+	o.MaxBytes = commandSize("show version") + 1
+	assert.Equal(t, [][]int{{0}, {1}, {2}, {-1, 3}}, splitIndices(splitCommands([]string{"show version", "show clock", "interface eth1/1", "  mtu 9216"}, o)))
+
+	// Blank lines do not separate sub-commands from their mode command
+	o.MaxBytes = 0
+	o.MaxCommands = 3
+	requests, err = splitCommands([]string{"interface eth1/1", "", "  mtu 9216", "  shutdown", "interface eth1/2", "  mtu 9216"}, o)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 2, 3}, {4, 5}}, splitIndices(requests, err))
+	o.MaxCommands = 2
+	requests, err = splitCommands([]string{"interface eth1/1", "", "  mtu 9216", "  shutdown"}, o)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 2}, {-1, 3}}, splitIndices(requests, err))
+
+	// Nested blocks are split, re-entering all enclosing modes
+	commands = []string{
+		"configure terminal",
+		"router bgp 1",
+		"  neighbor 1.1.1.1",
+		"    remote-as 2",
+		"    address-family ipv4 unicast",
+		"      route-map A in",
+		"      route-map B out",
+		"    description peer",
+		"  neighbor 2.2.2.2",
+		"    remote-as 3",
+	}
+	o.MaxCommands = 5
+	requests, err = splitCommands(commands, o)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]int{
+		{0, 1, 2, 3, 4},
+		{-1, -1, -1, -1, 5},
+		{-1, -1, -1, -1, 6},
+		{-1, -1, -1, 7, 8},
+		{-1, -1, -1, 9},
+	}, splitIndices(requests, err))
+	assert.Equal(t, []string{"configure terminal", "router bgp 1", "  neighbor 1.1.1.1", "    address-family ipv4 unicast", "      route-map B out"}, requests[2].cmds)
+	assert.Equal(t, []string{"configure terminal", "router bgp 1", "  neighbor 1.1.1.1", "    description peer", "  neighbor 2.2.2.2"}, requests[3].cmds)
+	assert.Equal(t, []string{"configure terminal", "router bgp 1", "  neighbor 2.2.2.2", "    remote-as 3"}, requests[4].cmds)
+
+	// The limit must hold the re-entered modes and one more command
+	o.MaxCommands = 4
+	_, err = splitCommands(commands, o)
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	o.MaxCommands = 1
+	_, err = splitCommands([]string{"configure terminal", "feature bgp"}, o)
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	assert.True(t, isConfigureTerminal("conf t"))
+	assert.True(t, isConfigureTerminal("configure terminal"))
+	assert.False(t, isConfigureTerminal("configure replace bootflash:cfg"))
+}
+
+// TestClientJsonRpcSplit tests the Client::JsonRpcSplit method.
+func TestClientJsonRpcSplit(t *testing.T) {
+	defer gock.Off()
+	client := testClient()
+	commands := []string{"configure terminal", "interface eth1/1", "  mtu 9216", "interface eth1/2", "  mtu 9999", "interface eth1/3", "  mtu 9216"}
+
+	// Stop at first failure
+	gock.New(testURL).Post("/ins").
+		BodyString(`"cmd":"configure terminal".*"cmd":"interface eth1/1".*"rollback":"stop-on-error"`).
+		Reply(200).
+		BodyString(`[{"jsonrpc":"2.0","result":null,"id":1},{"jsonrpc":"2.0","result":null,"id":2},{"jsonrpc":"2.0","result":null,"id":3}]`)
+	gock.New(testURL).Post("/ins").
+		BodyString(`"cmd":"configure terminal".*"cmd":"interface eth1/2"`).
+		Reply(200).
+		BodyString(`[{"jsonrpc":"2.0","result":null,"id":1},{"jsonrpc":"2.0","result":null,"id":2},{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":3}]`)
+	results, err := client.JsonRpcSplit(commands, SplitMaxCommands(3))
+	var rpcErr *JsonRpcError
+	assert.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "  mtu 9999", rpcErr.Command)
+	assert.Len(t, results, 5)
+	assert.Equal(t, 4, results[4].Index)
+	assert.Equal(t, 1, results[4].Request)
+	assert.Equal(t, "  mtu 9999", results[4].Command)
+	assert.True(t, gock.IsDone())
+
+	// Continue after failure
+	gock.New(testURL).Post("/ins").BodyString(`"rollback":"continue-on-error"`).Reply(200).BodyString(`[{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":1}]`)
+	gock.New(testURL).Post("/ins").BodyString(`"rollback":"continue-on-error"`).Reply(200).BodyString(`[{"jsonrpc":"2.0","result":null,"id":1}]`)
+	results, err = client.JsonRpcSplit([]string{"show foo", "show version"}, SplitMaxCommands(1), SplitOnFailure(ContinueOnFailure))
+	assert.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "show foo", rpcErr.Command)
+	assert.Len(t, results, 2)
+	assert.Equal(t, 1, results[1].Index)
+	assert.True(t, gock.IsDone())
+
+	// Roll back all requests
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"checkpoint nxos-`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"interface eth1/1".*"rollback":"rollback-on-error"`).Reply(200).BodyString(`[{"jsonrpc":"2.0","result":null,"id":1},{"jsonrpc":"2.0","result":null,"id":2},{"jsonrpc":"2.0","result":null,"id":3}]`)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"interface eth1/2"`).Reply(200).BodyString(`[{"jsonrpc":"2.0","result":null,"id":1},{"jsonrpc":"2.0","result":null,"id":2},{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":3}]`)
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"rollback running-config checkpoint nxos-`).Reply(200).BodyString(cliAsciiReply("Rollback completed successfully.\n"))
+	gock.New(testURL).Post("/ins").BodyString(`"cmd":"no checkpoint nxos-`).Reply(200).BodyString(cliAsciiReply("Done\n"))
+	_, err = client.JsonRpcSplit(commands, SplitMaxCommands(3), SplitOnFailure(RollbackOnFailure))
+	assert.ErrorContains(t, err, "rolled back to checkpoint nxos-")
+	assert.ErrorAs(t, err, &rpcErr)
+	assert.True(t, gock.IsDone())
+
+	// Invalid options
+	_, err = client.JsonRpcSplit(commands, SplitMaxCommands(0))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, err = client.JsonRpcSplit(commands, SplitOnFailure("ignore"))
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
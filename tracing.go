@@ -0,0 +1,48 @@
+package nxos
+
+import (
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation name used for OpenTelemetry tracers.
+const tracerName = "github.com/netascode/go-nxos"
+
+// Span attribute keys used in addition to the OpenTelemetry semantic conventions.
+const (
+	AttrDn        = "nxos.dn"
+	AttrAPI       = "nxos.api"
+	AttrErrorCode = "nxos.error_code"
+	AttrAttempt   = "nxos.attempt"
+	AttrDelay     = "nxos.backoff_delay_ms"
+	AttrCommands  = "nxos.commands"
+)
+
+// TracerProvider enables OpenTelemetry tracing using the given tracer provider.
+// Spans are created for Do, Login, Refresh, JsonRpc and ins_api requests, with child spans for
+// every HTTP attempt and backoff delay, e.g.
+//
+//	client, _ := nxos.NewClient("1.1.1.1", "user", "pwd", true, nxos.TracerProvider(otel.GetTracerProvider()))
+func TracerProvider(tp trace.TracerProvider) func(*Client) {
+	return func(client *Client) {
+		client.Tracer = tp.Tracer(tracerName)
+	}
+}
+
+// tracer returns the configured tracer or a no-op tracer.
+func (client *Client) tracer() trace.Tracer {
+	if client.Tracer == nil {
+		return noop.NewTracerProvider().Tracer(tracerName)
+	}
+	return client.Tracer
+}
+
+// endSpan records err on the span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
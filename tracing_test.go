@@ -0,0 +1,74 @@
+package nxos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// spanAttr returns the value of a span attribute.
+func spanAttr(span tracetest.SpanStub, key string) attribute.Value {
+	for _, attr := range span.Attributes {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// TestTracerProvider tests the TracerProvider modifier.
+func TestTracerProvider(t *testing.T) {
+	defer gock.Off()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := testClient()
+	TimeSource(&fakeClock{now: time.Now()})(client)
+	TracerProvider(tp)(client)
+	client.MaxRetries = 1
+	client.Token = "token"
+
+	gock.New(testURL).Get("/api/mo/sys/bgp.json").Reply(503)
+	gock.New(testURL).
+		Get("/api/mo/sys/bgp.json").
+		Reply(400).
+		BodyString(Body{}.Set("imdata.0.error.attributes.code", "107").Str)
+	_, err := client.GetDn("sys/bgp")
+	assert.Error(t, err)
+
+	spans := exporter.GetSpans()
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"nxos.attempt", "nxos.backoff", "nxos.attempt", "nxos.Do"}, names)
+
+	do := spans[3]
+	assert.Equal(t, codes.Error, do.Status.Code)
+	assert.Equal(t, "sys/bgp", spanAttr(do, AttrDn).AsString())
+	assert.Equal(t, "107", spanAttr(do, AttrErrorCode).AsString())
+	assert.Equal(t, int64(400), spanAttr(do, "http.response.status_code").AsInt64())
+	assert.Equal(t, int64(1), spanAttr(do, AttrAttempt).AsInt64())
+	for _, span := range spans[:3] {
+		assert.Equal(t, do.SpanContext.SpanID(), span.Parent.SpanID())
+	}
+	assert.Equal(t, int64(503), spanAttr(spans[0], "http.response.status_code").AsInt64())
+
+	// Login and JSON-RPC spans
+	exporter.Reset()
+	gock.New(testURL).Post("/api/aaaLogin.json").Reply(200)
+	assert.NoError(t, client.Login())
+	gock.New(testURL).Post("/ins").Reply(200)
+	_, err = client.JsonRpc([]string{"show version", "show clock"})
+	assert.NoError(t, err)
+	spans = exporter.GetSpans()
+	assert.Equal(t, "nxos.Login", spans[0].Name)
+	jsonRpc := spans[len(spans)-1]
+	assert.Equal(t, "nxos.JsonRpc", jsonRpc.Name)
+	assert.Equal(t, int64(2), spanAttr(jsonRpc, AttrCommands).AsInt64())
+}